@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	path "path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/cisordeng/bee/cmd/commands"
@@ -72,20 +73,30 @@ var CmdApiapp = &commands.Command{
 	PreRun: func(cmd *commands.Command, args []string) { version.ShowShortVersionBanner() },
 	Run:    createAPI,
 }
-var gitIgnore = `.idea/
+
+// apiTemplateDoc describes the data of the api templates
+const apiTemplateDoc = `
+Data of the templates of "bee api", e.g. for bee api hello 8081:
+
+  .Appname    Name of the application, e.g. hello
+  .Appport    HTTP port of the application, e.g. 8081
+  .GoVersion  Version of Go in the go.mod file, e.g. 1.12
+`
+
+var gitIgnore = generate.NewTemplate("api", "gitignore", apiTemplateDoc, `.idea/
 *.tmp
 {{.Appname}}
-`
+`)
 
-var dockerFile = `FROM golang:1.10.4
+var dockerFile = generate.NewTemplate("api", "dockerfile", apiTemplateDoc, `FROM golang:1.10.4
 
 ENV APP {{.Appname}}
 ADD ./ /go/src/$APP
 WORKDIR /go/src/$APP
 ENTRYPOINT ["go", "run", "main.go"]
-`
+`)
 
-var dockerSh = `#!/bin/bash
+var dockerSh = generate.NewTemplate("api", "docker_sh", apiTemplateDoc, `#!/bin/bash
 
 APP=${PWD##*/}
 
@@ -140,9 +151,9 @@ then
 else
   update
 fi
-`
+`)
 
-var demoCmd = `package cmd
+var demoCmd = generate.NewTemplate("api", "demo_cmd", apiTemplateDoc, `package cmd
 
 import (
 	"context"
@@ -157,9 +168,9 @@ func DemoCmd(ctx context.Context) {
 
 func init() {
 	xenon.RegisterCmd("demo_cmd", DemoCmd)
-}`
+}`)
 
-var demoTask = `package cron
+var demoTask = generate.NewTemplate("api", "demo_task", apiTemplateDoc, `package cron
 
 import (
 	"context"
@@ -172,9 +183,9 @@ func DemoTask(ctx context.Context) {
 
 func init() {
 	//xenon.RegisterCronTask("demo_task", "*/5 * * * *", DemoTask)
-}`
+}`)
 
-var apiConf = `appname = {{.Appname}}
+var apiConf = generate.NewTemplate("api", "conf", apiTemplateDoc, `appname = {{.Appname}}
 httpport = {{.Appport}}
 runmode = dev
 autorender = false
@@ -196,8 +207,8 @@ enableSign = true
 signSecret = 7d736a2822f8c005a8f034b477b23f27
 signEffectiveSeconds = 15
 aesCommonKey = 7d736a2822f8c005a8f034b477b23f27
-`
-var apiMain = `package main
+`)
+var apiMain = generate.NewTemplate("api", "main", apiTemplateDoc, `package main
 
 import (
 	"os"
@@ -213,9 +224,9 @@ import (
 func main() {
 	xenon.Run(os.Args)
 }
-`
+`)
 
-var apiRest = `package account
+var apiRest = generate.NewTemplate("api", "rest_user", apiTemplateDoc, `package account
 
 import (
 	"github.com/cisordeng/beego/xenon"
@@ -254,9 +265,9 @@ func (this *User) Put() {
 	data := bUser.EncodeUser(user)
 	this.ReturnJSON(data)
 }
-`
+`)
 
-var apiRestLogin = `package account
+var apiRestLogin = generate.NewTemplate("api", "rest_login_user", apiTemplateDoc, `package account
 
 import (
 	"github.com/cisordeng/beego/xenon"
@@ -298,9 +309,9 @@ func (this *LoginUser) Put() {
 		xenon.RaiseException("rest:name or password is wrong", "用户名或密码错误")
 	}
 }
-`
+`)
 
-var apiRestInit = `package rest
+var apiRestInit = generate.NewTemplate("api", "rest_init", apiTemplateDoc, `package rest
 
 import (
 	_ "{{.Appname}}/rest/account"
@@ -308,9 +319,9 @@ import (
 
 func init() {
 }
-`
+`)
 
-var apiModel = `package account
+var apiModel = generate.NewTemplate("api", "model_user", apiTemplateDoc, `package account
 
 import (
 	"time"
@@ -333,9 +344,9 @@ func (o *User) TableName() string {
 func init() {
 	orm.RegisterModel(new(User))
 }
-`
+`)
 
-var apiModelInit = `package model
+var apiModelInit = generate.NewTemplate("api", "model_init", apiTemplateDoc, `package model
 
 import (
 	_ "{{.Appname}}/model/account"
@@ -343,9 +354,9 @@ import (
 
 func init() {
 }
-`
+`)
 
-var apiBusiness = `package account
+var apiBusiness = generate.NewTemplate("api", "business_user", apiTemplateDoc, `package account
 
 import (
 	"time"
@@ -389,9 +400,9 @@ func NewUser(name string, password string, avatar string) (user *User) {
 	xenon.PanicNotNilError(err)
 	return InitUserFromModel(&model)
 }
-`
+`)
 
-var apiBusinessRepository = `package account
+var apiBusinessRepository = generate.NewTemplate("api", "business_repository", apiTemplateDoc, `package account
 
 import (
 	"github.com/cisordeng/beego/orm"
@@ -409,9 +420,9 @@ func GetUserByName(name string) (user *User)  {
 	user = InitUserFromModel(&model)
 	return user
 }
-`
+`)
 
-var apiBusinessEncode = `package account
+var apiBusinessEncode = generate.NewTemplate("api", "business_encode", apiTemplateDoc, `package account
 
 import (
 	"github.com/cisordeng/beego/xenon"
@@ -426,9 +437,9 @@ func EncodeUser(user *User) xenon.Map {
 	}
 	return mapUser
 }
-`
+`)
 
-var apiBusinessAuth = `package account
+var apiBusinessAuth = generate.NewTemplate("api", "business_auth", apiTemplateDoc, `package account
 
 import (
 	"encoding/json"
@@ -452,13 +463,18 @@ func AuthUser(name string, password string) string {
 	}
 	return ""
 }
-`
+`)
 
-var apiBusinessInit = `package business
+var apiBusinessInit = generate.NewTemplate("api", "business_init", apiTemplateDoc, `package business
 
 func init() {
 }
-`
+`)
+
+var apiGoMod = generate.NewTemplate("api", "go_mod", apiTemplateDoc, `module {{.Appname}}
+
+go {{.GoVersion}}
+`)
 
 func init() {
 	CmdApiapp.Flag.Var(&generate.Tables, "tables", "List of table names separated by a comma.")
@@ -481,7 +497,21 @@ func createAPI(cmd *commands.Command, args []string) int {
 		}
 	}
 
-	appPath, _, err := utils.CheckEnv(args[0])
+	currpath, _ := os.Getwd()
+	isModule := utils.UseModules(currpath)
+
+	var (
+		appPath string
+		err     error
+	)
+	if isModule {
+		appPath = path.Join(currpath, args[0])
+		if utils.IsExist(appPath) {
+			err = fmt.Errorf("cannot create application without removing '%s' first", appPath)
+		}
+	} else {
+		appPath, _, err = utils.CheckEnv(args[0])
+	}
 	appName := path.Base(args[0])
 	appPort := "8080"
 	if len(args) > 1 {
@@ -496,6 +526,12 @@ func createAPI(cmd *commands.Command, args []string) int {
 
 	beeLogger.Log.Info("Creating API...")
 
+	data := map[string]string{
+		"Appname":   appName,
+		"Appport":   appPort,
+		"GoVersion": goVersion(),
+	}
+
 	os.MkdirAll(appPath, 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", appPath, "\x1b[0m")
 	os.Mkdir(path.Join(appPath, "cmd"), 0755)
@@ -514,80 +550,97 @@ func createAPI(cmd *commands.Command, args []string) int {
 	// cmd
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "cmd", "demo_cmd.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "cmd", "demo_cmd.go"),
-		strings.Replace(demoCmd, "{{.Appname}}", appName, -1))
+		demoCmd.Render(currpath, data))
 
 	// cron
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "cron", "demo_task.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "cron", "demo_task.go"),
-		strings.Replace(demoTask, "{{.Appname}}", appName, -1))
+		demoTask.Render(currpath, data))
 
 	// config
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf", "app.conf"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "conf", "app.conf"),
-		strings.Replace(strings.Replace(apiConf, "{{.Appname}}", appName, -1), "{{.Appport}}", appPort, -1))
+		apiConf.Render(currpath, data))
 
 	// rest
 	os.Mkdir(path.Join(appPath, "rest", "account"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "rest", "account"), "\x1b[0m")
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "rest", "account", "user.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "rest", "account", "user.go"),
-		strings.Replace(apiRest, "{{.Appname}}", appName, -1))
+		apiRest.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "rest", "account", "login_user.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "rest", "account", "login_user.go"),
-		strings.Replace(apiRestLogin, "{{.Appname}}", appName, -1))
+		apiRestLogin.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "rest", "init.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "rest", "init.go"),
-		strings.Replace(apiRestInit, "{{.Appname}}", appName, -1))
+		apiRestInit.Render(currpath, data))
 
 	// business
 	os.Mkdir(path.Join(appPath, "business", "account"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "business", "account"), "\x1b[0m")
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "business", "account", "user.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "business", "account", "user.go"),
-		strings.Replace(apiBusiness, "{{.Appname}}", appName, -1))
+		apiBusiness.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "business", "account", "user_repository.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "business", "account", "user_repository.go"),
-		strings.Replace(apiBusinessRepository, "{{.Appname}}", appName, -1))
+		apiBusinessRepository.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "business", "account", "encode_user.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "business", "account", "encode_user.go"),
-		strings.Replace(apiBusinessEncode, "{{.Appname}}", appName, -1))
+		apiBusinessEncode.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "business", "account", "auth_user_service.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "business", "account", "auth_user_service.go"),
-		strings.Replace(apiBusinessAuth, "{{.Appname}}", appName, -1))
+		apiBusinessAuth.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "business", "init.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "business", "init.go"),
-		strings.Replace(apiBusinessInit, "{{.Appname}}", appName, -1))
+		apiBusinessInit.Render(currpath, data))
 
 	// model
 	os.Mkdir(path.Join(appPath, "model", "account"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "model", "account"), "\x1b[0m")
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "model", "account", "user.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "model", "account", "user.go"), apiModel)
+	utils.WriteToFile(path.Join(appPath, "model", "account", "user.go"), apiModel.Render(currpath, data))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "model", "init.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "model", "init.go"),
-		strings.Replace(apiModelInit, "{{.Appname}}", appName, -1))
+		apiModelInit.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "main.go"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "main.go"),
-		strings.Replace(apiMain, "{{.Appname}}", appName, -1))
+		apiMain.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, ".gitignore"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, ".gitignore"),
-		strings.Replace(gitIgnore, "{{.Appname}}", appName, -1))
+		gitIgnore.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "Dockerfile"), "\x1b[0m")
 	utils.WriteToFile(path.Join(appPath, "Dockerfile"),
-		strings.Replace(dockerFile, "{{.Appname}}", appName, -1))
+		dockerFile.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "docker.sh"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "docker.sh"), dockerSh)
+	utils.WriteToFile(path.Join(appPath, "docker.sh"), dockerSh.Render(currpath, data))
 
 	err = os.Chmod(path.Join(appPath, "docker.sh"), 0775)
 	if err != nil {
 		beeLogger.Log.Fatal("Failed to add execute permission to [docker.sh]")
 	}
 
+	if isModule {
+		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "go.mod"), "\x1b[0m")
+		utils.WriteToFile(path.Join(appPath, "go.mod"),
+			apiGoMod.Render(currpath, data))
+		beeLogger.Log.Hintf("Run 'go mod tidy' inside '%s' to resolve the dependencies", appPath)
+	}
 
 	beeLogger.Log.Success("New API successfully created!")
 	return 0
 }
+
+// goVersion returns the major.minor version of the Go toolchain,
+// as expected by the go directive of a go.mod file
+func goVersion() string {
+	v := strings.TrimPrefix(runtime.Version(), "go")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return "1.12"
+	}
+	return parts[0] + "." + strings.TrimFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+}
@@ -21,11 +21,11 @@ import (
 	"net"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/cisordeng/bee/cmd/commands"
 	"github.com/cisordeng/bee/cmd/commands/version"
+	"github.com/cisordeng/bee/config"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/cisordeng/bee/utils"
 	"github.com/derekparker/delve/service"
@@ -104,15 +104,16 @@ func loadPathsToWatch(paths *[]string) error {
 	if err != nil {
 		return err
 	}
-	filepath.Walk(directory, func(path string, info os.FileInfo, _ error) error {
-		if strings.HasSuffix(info.Name(), "docs") {
-			return filepath.SkipDir
+	matcher := utils.LoadIgnoreMatcher(directory, []string{"*docs", "*swagger", "*vendor"}, config.Conf.Watch.Exclude, config.Conf.Watch.Include)
+	filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
 		}
-		if strings.HasSuffix(info.Name(), "swagger") {
-			return filepath.SkipDir
-		}
-		if strings.HasSuffix(info.Name(), "vendor") {
-			return filepath.SkipDir
+		if rel, _ := filepath.Rel(directory, path); matcher.Match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		if filepath.Ext(info.Name()) == ".go" {
@@ -20,19 +20,33 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
-	"text/template"
 
 	"github.com/cisordeng/bee/cmd/commands"
 	"github.com/cisordeng/bee/cmd/commands/version"
+	"github.com/cisordeng/bee/generate"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/cisordeng/bee/utils"
 )
 
-const dockerBuildTemplate = `FROM {{.BaseImage}}
+// dockerTemplateDoc describes the data of the Dockerfile template
+const dockerTemplateDoc = `
+Data of the Dockerfile template of "bee dockerize":
 
+  .BaseImage   Base image of the container, given by -image
+  .Appdir      Directory of the application in the GOPATH, e.g. /src/github.com/me/app
+  .Entrypoint  Name of the binary of the application
+  .Expose      Ports exposed by the container, given by -expose
+  .Module      Whether the application is a Go module
+`
+
+var dockerBuildTemplate = generate.NewTemplate("dockerize", "dockerfile", dockerTemplateDoc, `FROM {{.BaseImage}}
+{{if .Module}}
+# Build with Go modules
+ENV GO111MODULE on
+{{else}}
 # Godep for vendoring
 RUN go get github.com/tools/godep
-
+{{end}}
 # Recompile the standard library without CGO
 RUN CGO_ENABLED=0 go install -a std
 
@@ -42,12 +56,15 @@ RUN mkdir -p $APP_DIR
 # Set the entrypoint
 ENTRYPOINT (cd $APP_DIR && ./{{.Entrypoint}})
 ADD . $APP_DIR
-
+{{if .Module}}
+# Download the dependencies, compile the binary and statically link
+RUN cd $APP_DIR && go mod download && CGO_ENABLED=0 go build -ldflags '-d -w -s'
+{{else}}
 # Compile the binary and statically link
 RUN cd $APP_DIR && CGO_ENABLED=0 godep go build -ldflags '-d -w -s'
-
+{{end}}
 EXPOSE {{.Expose}}
-`
+`)
 
 // Dockerfile holds the information about the Docker container.
 type Dockerfile struct {
@@ -55,6 +72,7 @@ type Dockerfile struct {
 	Appdir     string
 	Entrypoint string
 	Expose     string
+	Module     bool
 }
 
 var CmdDockerize = &commands.Command{
@@ -64,6 +82,9 @@ var CmdDockerize = &commands.Command{
 	Long: `Dockerize generates a Dockerfile for your Beego Web Application.
   The Dockerfile will compile, get the dependencies with {{"godep"|bold}}, and set the entrypoint.
 
+  An existing Dockerfile is only overwritten once confirmed, or with {{"-force"|bold}}.
+  {{"-dry-run"|bold}} and {{"-diff"|bold}} show what would be written, without writing it.
+
   {{"Example:"|bold}}
     $ bee dockerize -expose="3000,80,25"
   `,
@@ -80,6 +101,9 @@ func init() {
 	fs := flag.NewFlagSet("dockerize", flag.ContinueOnError)
 	fs.StringVar(&baseImage, "image", "library/golang", "Set the base image of the Docker container.")
 	fs.StringVar(&expose, "expose", "8080", "Port(s) to expose in the Docker container.")
+	fs.BoolVar(&generate.DryRun, "dry-run", false, "Show whether the Dockerfile would be written, without writing it.")
+	fs.BoolVar(&generate.Diff, "diff", false, "Show the changes to the existing Dockerfile, without writing it.")
+	fs.BoolVar(&generate.Force, "force", false, "Overwrite the existing Dockerfile without asking.")
 	CmdDockerize.Flag = *fs
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdDockerize)
 }
@@ -99,6 +123,15 @@ func dockerizeApp(cmd *commands.Command, args []string) int {
 
 	appdir := strings.Replace(dir, gopath, "", 1)
 
+	// Inside a Go module the application directory is derived
+	// from the module path, no matter where the sources live.
+	isModule := false
+	if found, root, modPath := utils.GetModule(dir); found {
+		rel, _ := filepath.Rel(root, dir)
+		appdir = path.Join("/src", modPath, filepath.ToSlash(rel))
+		isModule = true
+	}
+
 	// In case of multiple ports to expose inside the container,
 	// replace all the commas with whitespaces.
 	// See the verb EXPOSE in the Docker documentation.
@@ -112,6 +145,7 @@ func dockerizeApp(cmd *commands.Command, args []string) int {
 		Appdir:     appdir,
 		Entrypoint: entrypoint,
 		Expose:     expose,
+		Module:     isModule,
 	}
 
 	generateDockerfile(dockerfile)
@@ -119,15 +153,14 @@ func dockerizeApp(cmd *commands.Command, args []string) int {
 }
 
 func generateDockerfile(df Dockerfile) {
-	t := template.Must(template.New("dockerBuildTemplate").Parse(dockerBuildTemplate)).Funcs(utils.BeeFuncMap())
+	currpath, _ := os.Getwd()
 
-	f, err := os.Create("Dockerfile")
-	if err != nil {
-		beeLogger.Log.Fatalf("Error writing Dockerfile: %v", err.Error())
+	plan := &generate.Plan{}
+	plan.Add(filepath.Join(currpath, "Dockerfile"), dockerBuildTemplate.Render(currpath, df))
+	plan.Apply()
+	if generate.DryRun || generate.Diff {
+		return
 	}
-	defer utils.CloseFile(f)
-
-	t.Execute(f, df)
 
 	beeLogger.Log.Success("Dockerfile generated.")
 }
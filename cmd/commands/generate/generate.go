@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	"github.com/cisordeng/bee/cmd/commands"
+	"github.com/cisordeng/bee/cmd/commands/migrate"
 	"github.com/cisordeng/bee/cmd/commands/version"
 	"github.com/cisordeng/bee/config"
 	"github.com/cisordeng/bee/generate"
@@ -37,6 +38,10 @@ var CmdGenerate = &commands.Command{
 
      $ bee generate model [modelname] [-fields="name:type"]
 
+  ▶ {{"To generate a xenon resource with its business and model code:"|bold}}
+
+     $ bee generate resource [package.resource] [-fields="name:string:size(64),age:int,email:string:unique"] [-crud]
+
   ▶ {{"To generate a controller:"|bold}}
 
      $ bee generate controller [controllerfile]
@@ -59,7 +64,30 @@ var CmdGenerate = &commands.Command{
 
   ▶ {{"To generate appcode based on an existing database:"|bold}}
 
-     $ bee generate appcode [-tables=""] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"] [-level=3]
+     $ bee generate appcode [-tables=""] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"] [-level=3] [-layout=beego]
+
+     With -layout=xenon, the table account_user is generated as the resource account.user,
+     in the rest, business and model directories like "bee generate resource" does.
+
+  ▶ {{"To override the templates of the generators:"|bold}}
+
+     $ bee generate templates export [dir]
+     $ bee generate templates list
+
+     The templates are looked up in the .bee/templates directory of the project, then in
+     the one of the user, e.g. .bee/templates/resource/model.tmpl, before the built-in ones.
+     They are rendered with text/template. export writes the built-in templates, each with
+     the description of its data, to .bee/templates or dir, as a starting point.
+
+  ▶ {{"To check what a generator would write before writing it:"|bold}}
+
+     $ bee generate resource account.user -dry-run
+     $ bee generate resource account.user -fields="name:string" -diff
+
+     The generators never overwrite an existing file which differs from the generated one,
+     unless it is confirmed, or -force is given. -dry-run lists the files, and whether they
+     would be created or overwritten, and -diff shows the changes to the existing files.
+     Neither of them writes anything.
 `,
 	PreRun: func(cmd *commands.Command, args []string) { version.ShowShortVersionBanner() },
 	Run:    GenerateCode,
@@ -70,8 +98,13 @@ func init() {
 	CmdGenerate.Flag.Var(&generate.SQLDriver, "driver", "Database SQLDriver. Either mysql, postgres or sqlite.")
 	CmdGenerate.Flag.Var(&generate.SQLConn, "conn", "Connection string used by the SQLDriver to connect to a database instance.")
 	CmdGenerate.Flag.Var(&generate.Level, "level", "Either 1, 2 or 3. i.e. 1=models; 2=models and controllers; 3=models, controllers and routers.")
+	CmdGenerate.Flag.Var(&generate.Layout, "layout", "Either beego or xenon. i.e. beego=models, controllers and routers; xenon=rest, business and model resources.")
 	CmdGenerate.Flag.Var(&generate.Fields, "fields", "List of table Fields.")
 	CmdGenerate.Flag.Var(&generate.DDL, "ddl", "Generate DDL Migration")
+	CmdGenerate.Flag.BoolVar(&generate.CRUD, "crud", false, "Generate the Put, Post and Delete handlers of a resource.")
+	CmdGenerate.Flag.BoolVar(&generate.DryRun, "dry-run", false, "List the files which would be generated, without writing them.")
+	CmdGenerate.Flag.BoolVar(&generate.Diff, "diff", false, "Show the changes to the existing files, without writing them.")
+	CmdGenerate.Flag.BoolVar(&generate.Force, "force", false, "Overwrite the existing files without asking.")
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdGenerate)
 }
 
@@ -81,14 +114,18 @@ func GenerateCode(cmd *commands.Command, args []string) int {
 		beeLogger.Log.Fatal("Command is missing")
 	}
 
-	gps := utils.GetGOPATHs()
-	if len(gps) == 0 {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
+	if found, _, modPath := utils.GetModule(currpath); found {
+		beeLogger.Log.Debugf("Module: %s", utils.FILE(), utils.LINE(), modPath)
+	} else {
+		gps := utils.GetGOPATHs()
+		if len(gps) == 0 {
+			beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
+		}
 
-	gopath := gps[0]
+		gopath := gps[0]
 
-	beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
+		beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
+	}
 
 	gcmd := args[0]
 	switch gcmd {
@@ -101,16 +138,22 @@ func GenerateCode(cmd *commands.Command, args []string) int {
 	case "migration":
 		migration(cmd, args, currpath)
 	case "controller":
-		controller(args, currpath)
+		controller(cmd, args, currpath)
 	case "model":
 		model(cmd, args, currpath)
 	case "view":
-		view(args, currpath)
+		view(cmd, args, currpath)
 	case "resource":
-		resource(args, currpath)
+		resource(cmd, args, currpath)
+	case "templates":
+		templates(cmd, args, currpath)
+		return 0
 	default:
 		beeLogger.Log.Fatal("Command is missing")
 	}
+	if generate.DryRun || generate.Diff {
+		return 0
+	}
 	beeLogger.Log.Successf("%s successfully generated!", strings.Title(gcmd))
 	return 0
 }
@@ -138,7 +181,17 @@ func scaffold(cmd *commands.Command, args []string, currpath string) {
 		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
 	}
 	sname := args[1]
-	generate.GenerateScaffold(sname, generate.Fields.String(), currpath, generate.SQLDriver.String(), generate.SQLConn.String())
+	generate.GenerateScaffold(sname, generate.Fields.String(), currpath)
+
+	// Run the migration, unless nothing was written
+	if generate.DryRun || generate.Diff {
+		return
+	}
+	beeLogger.Log.Infof("Do you want to migrate the database? [Yes|No] ")
+	if utils.AskForConfirmation() {
+		migrate.MigrateUpdate(currpath, generate.SQLDriver.String(), generate.SQLConn.String(), "")
+	}
+	beeLogger.Log.Successf("All done! Don't forget to add  beego.Router(\"/%s\" ,&controllers.%sController{}) to routers/route.go\n", sname, strings.Title(sname))
 }
 
 func appCode(cmd *commands.Command, args []string, currpath string) {
@@ -162,11 +215,15 @@ func appCode(cmd *commands.Command, args []string, currpath string) {
 	if generate.Level == "" {
 		generate.Level = "3"
 	}
+	if generate.Layout == "" {
+		generate.Layout = "beego"
+	}
 	beeLogger.Log.Infof("Using '%s' as 'SQLDriver'", generate.SQLDriver)
 	beeLogger.Log.Infof("Using '%s' as 'SQLConn'", generate.SQLConn)
 	beeLogger.Log.Infof("Using '%s' as 'Tables'", generate.Tables)
 	beeLogger.Log.Infof("Using '%s' as 'Level'", generate.Level)
-	generate.GenerateAppcode(generate.SQLDriver.String(), generate.SQLConn.String(), generate.Level.String(), generate.Tables.String(), currpath)
+	beeLogger.Log.Infof("Using '%s' as 'Layout'", generate.Layout)
+	generate.GenerateAppcode(generate.SQLDriver.String(), generate.SQLConn.String(), generate.Level.String(), generate.Layout.String(), generate.Tables.String(), currpath)
 }
 
 func migration(cmd *commands.Command, args []string, currpath string) {
@@ -188,13 +245,16 @@ func migration(cmd *commands.Command, args []string, currpath string) {
 	generate.GenerateMigration(mname, upsql, downsql, currpath)
 }
 
-func controller(args []string, currpath string) {
-	if len(args) == 2 {
-		cname := args[1]
-		generate.GenerateController(cname, currpath)
-	} else {
+func controller(cmd *commands.Command, args []string, currpath string) {
+	if len(args) < 2 {
+		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
+	}
+	cmd.Flag.Parse(args[2:])
+	if cmd.Flag.NArg() > 0 {
 		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
 	}
+	cname := args[1]
+	generate.GenerateController(cname, currpath)
 }
 
 func model(cmd *commands.Command, args []string, currpath string) {
@@ -210,20 +270,50 @@ func model(cmd *commands.Command, args []string, currpath string) {
 	generate.GenerateModel(sname, generate.Fields.String(), currpath)
 }
 
-func view(args []string, currpath string) {
-	if len(args) == 2 {
-		cname := args[1]
-		generate.GenerateView(cname, currpath)
-	} else {
+func view(cmd *commands.Command, args []string, currpath string) {
+	if len(args) < 2 {
 		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
 	}
+	cmd.Flag.Parse(args[2:])
+	if cmd.Flag.NArg() > 0 {
+		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
+	}
+	cname := args[1]
+	generate.GenerateView(cname, currpath)
 }
 
-func resource(args []string, currpath string) {
-	if len(args) == 2 {
-		cname := args[1]
-		generate.GenerateResource(cname, currpath)
-	} else {
+func resource(cmd *commands.Command, args []string, currpath string) {
+	if len(args) < 2 {
 		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
 	}
+	cmd.Flag.Parse(args[2:])
+	if cmd.Flag.NArg() > 0 {
+		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
+	}
+	cname := args[1]
+	generate.GenerateResource(cname, generate.Fields.String(), currpath)
+}
+
+func templates(cmd *commands.Command, args []string, currpath string) {
+	if len(args) < 2 {
+		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help generate")
+	}
+	switch args[1] {
+	case "export":
+		dir := generate.TemplateDirs(currpath)[0]
+		if len(args) > 2 && !strings.HasPrefix(args[2], "-") {
+			dir = args[2]
+			args = args[1:]
+		}
+		cmd.Flag.Parse(args[2:])
+		generate.ExportTemplates(dir)
+		if generate.DryRun || generate.Diff {
+			return
+		}
+		beeLogger.Log.Successf("Templates successfully exported to '%s'", dir)
+	case "list":
+		generate.ListTemplates(currpath)
+	default:
+		beeLogger.Log.Fatal("Unknown templates command. It should be export or list")
+	}
 }
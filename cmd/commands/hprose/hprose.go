@@ -5,7 +5,6 @@ import (
 
 	"fmt"
 	"path"
-	"strings"
 
 	"github.com/cisordeng/bee/cmd/commands"
 	"github.com/cisordeng/bee/cmd/commands/version"
@@ -48,128 +47,6 @@ func init() {
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdHproseapp)
 }
 
-var APIModels = `package models
-import (
-	"errors"
-	"strconv"
-	"time"
-)
-var (
-	Objects map[string]*Object
-)
-type Object struct {
-	ObjectId   string
-	Score      int64
-	PlayerName string
-}
-func init() {
-	Objects = make(map[string]*Object)
-	Objects["hjkhsbnmn123"] = &Object{"hjkhsbnmn123", 100, "cisordeng"}
-	Objects["mjjkxsxsaa23"] = &Object{"mjjkxsxsaa23", 101, "someone"}
-}
-func AddOne(object Object) (ObjectId string) {
-	object.ObjectId = "cisordeng" + strconv.FormatInt(time.Now().UnixNano(), 10)
-	Objects[object.ObjectId] = &object
-	return object.ObjectId
-}
-func GetOne(ObjectId string) (object *Object, err error) {
-	if v, ok := Objects[ObjectId]; ok {
-		return v, nil
-	}
-	return nil, errors.New("ObjectId Not Exist")
-}
-func GetAll() map[string]*Object {
-	return Objects
-}
-func Update(ObjectId string, Score int64) (err error) {
-	if v, ok := Objects[ObjectId]; ok {
-		v.Score = Score
-		return nil
-	}
-	return errors.New("ObjectId Not Exist")
-}
-func Delete(ObjectId string) {
-	delete(Objects, ObjectId)
-}
-`
-
-var APIModels2 = `package models
-import (
-	"errors"
-	"strconv"
-	"time"
-)
-var (
-	UserList map[string]*User
-)
-func init() {
-	UserList = make(map[string]*User)
-	u := User{"user_11111", "cisordeng", "11111", Profile{"male", 20, "Singapore", "cisordeng@gmail.com"}}
-	UserList["user_11111"] = &u
-}
-type User struct {
-	Id       string
-	Username string
-	Password string
-	Profile  Profile
-}
-type Profile struct {
-	Gender  string
-	Age     int
-	Address string
-	Email   string
-}
-func AddUser(u User) string {
-	u.Id = "user_" + strconv.FormatInt(time.Now().UnixNano(), 10)
-	UserList[u.Id] = &u
-	return u.Id
-}
-func GetUser(uid string) (u *User, err error) {
-	if u, ok := UserList[uid]; ok {
-		return u, nil
-	}
-	return nil, errors.New("User not exists")
-}
-func GetAllUsers() map[string]*User {
-	return UserList
-}
-func UpdateUser(uid string, uu *User) (a *User, err error) {
-	if u, ok := UserList[uid]; ok {
-		if uu.Username != "" {
-			u.Username = uu.Username
-		}
-		if uu.Password != "" {
-			u.Password = uu.Password
-		}
-		if uu.Profile.Age != 0 {
-			u.Profile.Age = uu.Profile.Age
-		}
-		if uu.Profile.Address != "" {
-			u.Profile.Address = uu.Profile.Address
-		}
-		if uu.Profile.Gender != "" {
-			u.Profile.Gender = uu.Profile.Gender
-		}
-		if uu.Profile.Email != "" {
-			u.Profile.Email = uu.Profile.Email
-		}
-		return u, nil
-	}
-	return nil, errors.New("User Not Exist")
-}
-func Login(username, password string) bool {
-	for _, u := range UserList {
-		if u.Username == username && u.Password == password {
-			return true
-		}
-	}
-	return false
-}
-func DeleteUser(uid string) {
-	delete(UserList, uid)
-}
-`
-
 func createhprose(cmd *commands.Command, args []string) int {
 	output := cmd.Out()
 
@@ -195,8 +72,11 @@ func createhprose(cmd *commands.Command, args []string) int {
 	os.Mkdir(path.Join(apppath, "conf"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "conf"), "\x1b[0m")
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "conf", "app.conf"), "\x1b[0m")
-	utils.WriteToFile(path.Join(apppath, "conf", "app.conf"),
-		strings.Replace(generate.Hproseconf, "{{.Appname}}", args[0], -1))
+	data := map[string]interface{}{
+		"Appname": args[0],
+		"PkgPath": packpath,
+	}
+	utils.WriteToFile(path.Join(apppath, "conf", "app.conf"), generate.Hproseconf.Render(curpath, data))
 
 	if generate.SQLConn != "" {
 		beeLogger.Log.Infof("Using '%s' as 'driver'", generate.SQLDriver)
@@ -205,35 +85,27 @@ func createhprose(cmd *commands.Command, args []string) int {
 		generate.GenerateHproseAppcode(string(generate.SQLDriver), string(generate.SQLConn), "1", string(generate.Tables), path.Join(curpath, args[0]))
 
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "main.go"), "\x1b[0m")
-		maingoContent := strings.Replace(generate.HproseMainconngo, "{{.Appname}}", packpath, -1)
-		maingoContent = strings.Replace(maingoContent, "{{.DriverName}}", string(generate.SQLDriver), -1)
-		maingoContent = strings.Replace(maingoContent, "{{HproseFunctionList}}", strings.Join(generate.HproseAddFunctions, ""), -1)
+		data["DriverName"] = string(generate.SQLDriver)
+		data["conn"] = generate.SQLConn.String()
+		data["Models"] = generate.HproseModelNames
 		if generate.SQLDriver == "mysql" {
-			maingoContent = strings.Replace(maingoContent, "{{.DriverPkg}}", `_ "github.com/go-sql-driver/mysql"`, -1)
+			data["DriverPkg"] = `_ "github.com/go-sql-driver/mysql"`
 		} else if generate.SQLDriver == "postgres" {
-			maingoContent = strings.Replace(maingoContent, "{{.DriverPkg}}", `_ "github.com/lib/pq"`, -1)
+			data["DriverPkg"] = `_ "github.com/lib/pq"`
 		}
-		utils.WriteToFile(path.Join(apppath, "main.go"),
-			strings.Replace(
-				maingoContent,
-				"{{.conn}}",
-				generate.SQLConn.String(),
-				-1,
-			),
-		)
+		utils.WriteToFile(path.Join(apppath, "main.go"), generate.HproseMainconngo.Render(curpath, data))
 	} else {
 		os.Mkdir(path.Join(apppath, "models"), 0755)
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "models"), "\x1b[0m")
 
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "models", "object.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(apppath, "models", "object.go"), APIModels)
+		utils.WriteToFile(path.Join(apppath, "models", "object.go"), generate.HproseModels.Render(curpath, data))
 
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "models", "user.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(apppath, "models", "user.go"), APIModels2)
+		utils.WriteToFile(path.Join(apppath, "models", "user.go"), generate.HproseModels2.Render(curpath, data))
 
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "main.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(apppath, "main.go"),
-			strings.Replace(generate.HproseMaingo, "{{.Appname}}", packpath, -1))
+		utils.WriteToFile(path.Join(apppath, "main.go"), generate.HproseMaingo.Render(curpath, data))
 	}
 	beeLogger.Log.Success("New Hprose application successfully created!")
 	return 0
@@ -19,13 +19,13 @@ import (
 	"os/exec"
 	"path"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cisordeng/bee/cmd/commands"
 	"github.com/cisordeng/bee/cmd/commands/version"
 	"github.com/cisordeng/bee/config"
+	"github.com/cisordeng/bee/generate"
 	"github.com/cisordeng/bee/utils"
 
 	beeLogger "github.com/cisordeng/bee/logger"
@@ -35,6 +35,7 @@ var CmdMigrate = &commands.Command{
 	UsageLine: "migrate [Command]",
 	Short:     "Runs database migrations",
 	Long: `The command 'migrate' allows you to run database migrations to keep it up-to-date.
+  The migrations run with the variables of the .env files added to their environment.
 
   ▶ {{"To run all the migrations:"|bold}}
 
@@ -71,14 +72,18 @@ func init() {
 func RunMigration(cmd *commands.Command, args []string) int {
 	currpath, _ := os.Getwd()
 
-	gps := utils.GetGOPATHs()
-	if len(gps) == 0 {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
+	if found, _, modPath := utils.GetModule(currpath); found {
+		beeLogger.Log.Debugf("Module: %s", utils.FILE(), utils.LINE(), modPath)
+	} else {
+		gps := utils.GetGOPATHs()
+		if len(gps) == 0 {
+			beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
+		}
 
-	gopath := gps[0]
+		gopath := gps[0]
 
-	beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
+		beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
+	}
 
 	// Getting command line arguments
 	if len(args) != 0 {
@@ -159,9 +164,9 @@ func migrate(goal, currpath, driver, connStr, dir string) {
 
 	checkForSchemaUpdateTable(db, driver)
 	latestName, latestTime := getLatestMigration(db, goal)
-	writeMigrationSourceFile(dir, source, driver, connStr, latestTime, latestName, goal)
+	writeMigrationSourceFile(currpath, dir, source, driver, connStr, latestTime, latestName, goal)
 	buildMigrationBinary(dir, binary)
-	runMigrationBinary(dir, binary)
+	runMigrationBinary(dir, binary, loadEnvFiles(currpath))
 	removeTempFile(dir, source)
 	removeTempFile(dir, binary)
 }
@@ -287,17 +292,19 @@ func getLatestMigration(db *sql.DB, goal string) (file string, createdAt int64)
 }
 
 // writeMigrationSourceFile create the source file based on MIGRATION_MAIN_TPL
-func writeMigrationSourceFile(dir, source, driver, connStr string, latestTime int64, latestName string, task string) {
+func writeMigrationSourceFile(currpath, dir, source, driver, connStr string, latestTime int64, latestName string, task string) {
 	changeDir(dir)
 	if f, err := os.OpenFile(source, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err != nil {
 		beeLogger.Log.Fatalf("Could not create file: %s", err)
 	} else {
-		content := strings.Replace(MigrationMainTPL, "{{DBDriver}}", driver, -1)
-		content = strings.Replace(content, "{{DriverRepo}}", driverImportStatement(driver), -1)
-		content = strings.Replace(content, "{{ConnStr}}", connStr, -1)
-		content = strings.Replace(content, "{{LatestTime}}", strconv.FormatInt(latestTime, 10), -1)
-		content = strings.Replace(content, "{{LatestName}}", latestName, -1)
-		content = strings.Replace(content, "{{Task}}", task, -1)
+		content := MigrationMainTPL.Render(currpath, map[string]interface{}{
+			"DBDriver":   driver,
+			"DriverRepo": driverImportStatement(driver),
+			"ConnStr":    connStr,
+			"LatestTime": latestTime,
+			"LatestName": latestName,
+			"Task":       task,
+		})
 		if _, err := f.WriteString(content); err != nil {
 			beeLogger.Log.Fatalf("Could not write to file: %s", err)
 		}
@@ -318,10 +325,21 @@ func buildMigrationBinary(dir, binary string) {
 	}
 }
 
+// loadEnvFiles returns the variables of the .env files of the application
+func loadEnvFiles(currpath string) []string {
+	env, err := utils.LoadEnvFiles(currpath, os.Getenv("BEEGO_RUNMODE"))
+	if err != nil {
+		beeLogger.Log.Warnf("Could not load the .env files: %s", err)
+	}
+	return env
+}
+
 // runMigrationBinary runs the migration program who does the actual work
-func runMigrationBinary(dir, binary string) {
+// with the variables of the .env files added to its environment
+func runMigrationBinary(dir, binary string, env []string) {
 	changeDir(dir)
 	cmd := exec.Command("./" + binary)
+	cmd.Env = append(os.Environ(), env...)
 	if out, err := cmd.CombinedOutput(); err != nil {
 		formatShellOutput(string(out))
 		beeLogger.Log.Errorf("Could not run migration binary: %s", err)
@@ -367,9 +385,21 @@ func formatShellOutput(o string) {
 	}
 }
 
-const (
-	// MigrationMainTPL migration main template
-	MigrationMainTPL = `package main
+// migrateTemplateDoc describes the data of the migrate template
+const migrateTemplateDoc = `
+Data of the template of the program which "bee migrate" builds and runs to
+apply the migrations of database/migrations:
+
+  .DBDriver    Database driver, e.g. mysql
+  .DriverRepo  Import path of the database driver, e.g. github.com/go-sql-driver/mysql
+  .ConnStr     Connection string of the database
+  .LatestTime  Creation time of the latest migration applied, e.g. 20190512150405
+  .LatestName  Name of the latest migration applied
+  .Task        Task to run: upgrade, rollback, reset or refresh
+`
+
+// MigrationMainTPL migration main template
+var MigrationMainTPL = generate.NewTemplate("migrate", "main", migrateTemplateDoc, `package main
 
 import(
 	"os"
@@ -377,22 +407,22 @@ import(
 	"github.com/cisordeng/beego/orm"
 	"github.com/cisordeng/beego/migration"
 
-	_ "{{DriverRepo}}"
+	_ "{{.DriverRepo}}"
 )
 
 func init(){
-	orm.RegisterDataBase("default", "{{DBDriver}}","{{ConnStr}}")
+	orm.RegisterDataBase("default", "{{.DBDriver}}","{{.ConnStr}}")
 }
 
 func main(){
-	task := "{{Task}}"
+	task := "{{.Task}}"
 	switch task {
 	case "upgrade":
-		if err := migration.Upgrade({{LatestTime}}); err != nil {
+		if err := migration.Upgrade({{.LatestTime}}); err != nil {
 			os.Exit(2)
 		}
 	case "rollback":
-		if err := migration.Rollback("{{LatestName}}"); err != nil {
+		if err := migration.Rollback("{{.LatestName}}"); err != nil {
 			os.Exit(2)
 		}
 	case "reset":
@@ -406,7 +436,9 @@ func main(){
 	}
 }
 
-`
+`)
+
+const (
 	// MYSQLMigrationDDL MySQL migration SQL
 	MYSQLMigrationDDL = `
 CREATE TABLE migrations (
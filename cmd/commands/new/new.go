@@ -18,10 +18,11 @@ import (
 	"fmt"
 	"os"
 	path "path/filepath"
-	"strings"
 
 	"github.com/cisordeng/bee/cmd/commands"
+	"github.com/cisordeng/bee/cmd/commands/run/livereload"
 	"github.com/cisordeng/bee/cmd/commands/version"
+	"github.com/cisordeng/bee/generate"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
@@ -57,15 +58,23 @@ Creates a Beego application for the given app name in the current directory.
 	Run:    CreateApp,
 }
 
-var appconf = `appname = {{.Appname}}
+// newTemplateDoc describes the data of the templates of "bee new"
+const newTemplateDoc = `
+Data of the templates of "bee new", e.g. for bee new hello:
+
+  .Appname  Name of the application, e.g. hello
+  .PkgPath  Import path of the application, e.g. hello or github.com/me/hello
+`
+
+var appconf = generate.NewTemplate("new", "conf", newTemplateDoc, `appname = {{.Appname}}
 httpport = 8080
 runmode = dev
-`
+`)
 
-var maingo = `package main
+var maingo = generate.NewTemplate("new", "main", newTemplateDoc, `package main
 
 import (
-	_ "{{.Appname}}/routers"
+	_ "{{.PkgPath}}/routers"
 	"github.com/cisordeng/beego"
 )
 
@@ -73,20 +82,20 @@ func main() {
 	beego.Run()
 }
 
-`
-var router = `package routers
+`)
+var router = generate.NewTemplate("new", "router", newTemplateDoc, `package routers
 
 import (
-	"{{.Appname}}/controllers"
+	"{{.PkgPath}}/controllers"
 	"github.com/cisordeng/beego"
 )
 
 func init() {
     beego.Router("/", &controllers.MainController{})
 }
-`
+`)
 
-var test = `package test
+var test = generate.NewTemplate("new", "test", newTemplateDoc, `package test
 
 import (
 	"net/http"
@@ -94,7 +103,7 @@ import (
 	"testing"
 	"runtime"
 	"path/filepath"
-	_ "{{.Appname}}/routers"
+	_ "{{.PkgPath}}/routers"
 
 	"github.com/cisordeng/beego"
 	. "github.com/smartystreets/goconvey/convey"
@@ -125,9 +134,9 @@ func TestBeego(t *testing.T) {
 	})
 }
 
-`
+`)
 
-var controllers = `package controllers
+var controllers = generate.NewTemplate("new", "controller", newTemplateDoc, `package controllers
 
 import (
 	"github.com/cisordeng/beego"
@@ -142,9 +151,9 @@ func (c *MainController) Get() {
 	c.Data["Email"] = "cisordeng@gmail.com"
 	c.TplName = "index.tpl"
 }
-`
+`)
 
-var indextpl = `<!DOCTYPE html>
+var indextpl = generate.NewTemplate("new", "index", newTemplateDoc, `<!DOCTYPE html>
 
 <html>
 <head>
@@ -229,9 +238,9 @@ var indextpl = `<!DOCTYPE html>
   <footer>
     <div class="author">
       Official website:
-      <a href="http://{{.Website}}">{{.Website}}</a> /
+      <a href="http://{{"{{.Website}}"}}">{{"{{.Website}}"}}</a> /
       Contact me:
-      <a class="email" href="mailto:{{.Email}}">{{.Email}}</a>
+      <a class="email" href="mailto:{{"{{.Email}}"}}">{{"{{.Email}}"}}</a>
     </div>
   </footer>
   <div class="backdrop"></div>
@@ -239,10 +248,7 @@ var indextpl = `<!DOCTYPE html>
   <script src="/static/js/reload.min.js"></script>
 </body>
 </html>
-`
-
-var reloadJsClient = `function b(a){var c=new WebSocket(a);c.onclose=function(){setTimeout(function(){b(a)},2E3)};c.onmessage=function(){location.reload()}}try{if(window.WebSocket)try{b("ws://localhost:12450/reload")}catch(a){console.error(a)}else console.log("Your browser does not support WebSockets.")}catch(a){console.error("Exception during connecting to Reload:",a)};
-`
+`)
 
 func init() {
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdNew)
@@ -269,6 +275,12 @@ func CreateApp(cmd *commands.Command, args []string) int {
 
 	beeLogger.Log.Info("Creating application...")
 
+	currpath, _ := os.Getwd()
+	data := map[string]interface{}{
+		"Appname": path.Base(args[0]),
+		"PkgPath": packPath,
+	}
+
 	os.MkdirAll(appPath, 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", appPath+string(path.Separator), "\x1b[0m")
 	os.Mkdir(path.Join(appPath, "conf"), 0755)
@@ -284,7 +296,7 @@ func CreateApp(cmd *commands.Command, args []string) int {
 	os.Mkdir(path.Join(appPath, "static"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static")+string(path.Separator), "\x1b[0m")
 	os.Mkdir(path.Join(appPath, "static", "js"), 0755)
-	utils.WriteToFile(path.Join(appPath, "static", "js", "reload.min.js"), reloadJsClient)
+	utils.WriteToFile(path.Join(appPath, "static", "js", "reload.min.js"), livereload.JsClient)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static", "js")+string(path.Separator), "\x1b[0m")
 	os.Mkdir(path.Join(appPath, "static", "css"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static", "css")+string(path.Separator), "\x1b[0m")
@@ -293,22 +305,22 @@ func CreateApp(cmd *commands.Command, args []string) int {
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "views")+string(path.Separator), "\x1b[0m")
 	os.Mkdir(path.Join(appPath, "views"), 0755)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf", "app.conf"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "conf", "app.conf"), strings.Replace(appconf, "{{.Appname}}", path.Base(args[0]), -1))
+	utils.WriteToFile(path.Join(appPath, "conf", "app.conf"), appconf.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "controllers", "default.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "controllers", "default.go"), controllers)
+	utils.WriteToFile(path.Join(appPath, "controllers", "default.go"), controllers.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "views", "index.tpl"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "views", "index.tpl"), indextpl)
+	utils.WriteToFile(path.Join(appPath, "views", "index.tpl"), indextpl.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "routers", "router.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "routers", "router.go"), strings.Replace(router, "{{.Appname}}", packPath, -1))
+	utils.WriteToFile(path.Join(appPath, "routers", "router.go"), router.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "tests", "default_test.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "tests", "default_test.go"), strings.Replace(test, "{{.Appname}}", packPath, -1))
+	utils.WriteToFile(path.Join(appPath, "tests", "default_test.go"), test.Render(currpath, data))
 
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "main.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "main.go"), strings.Replace(maingo, "{{.Appname}}", packPath, -1))
+	utils.WriteToFile(path.Join(appPath, "main.go"), maingo.Render(currpath, data))
 
 	beeLogger.Log.Success("New application successfully created!")
 	return 0
@@ -20,6 +20,7 @@ import (
 
 	"github.com/cisordeng/bee/cmd/commands"
 	"github.com/cisordeng/bee/cmd/commands/version"
+	"github.com/cisordeng/bee/config"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/cisordeng/bee/utils"
 )
@@ -30,6 +31,8 @@ var CmdPack = &commands.Command{
 	Short:       "Compresses a Beego application into a single file",
 	Long: `Pack is used to compress Beego applications into a tarball/zip file.
   This eases the deployment by directly extracting the file to a server.
+  Paths matching the gitignore-style patterns of the .beeignore file and of the
+  'watch.exclude' list of the Beefile are left out as well.
 
   {{"Example:"|bold}}
     $ bee pack -v -ba="-ldflags '-s -w'"
@@ -92,6 +95,7 @@ type walkFileTree struct {
 	excludePrefix []string
 	excludeRegexp []*regexp.Regexp
 	excludeSuffix []string
+	matcher       *utils.IgnoreMatcher
 	allfiles      map[string]bool
 	output        *io.Writer
 }
@@ -111,6 +115,10 @@ func (wft *walkFileTree) isExclude(fPath string) bool {
 			return true
 		}
 	}
+	if wft.matcher != nil {
+		fi, err := os.Stat(path.Join(wft.prefix, fPath))
+		return wft.matcher.Match(fPath, err == nil && fi.IsDir())
+	}
 	return false
 }
 
@@ -354,7 +362,7 @@ func (wft *zipWalk) compress(name, fpath string, fi os.FileInfo) (bool, error) {
 }
 
 func packDirectory(output io.Writer, excludePrefix []string, excludeSuffix []string,
-	excludeRegexp []*regexp.Regexp, includePath ...string) (err error) {
+	excludeRegexp []*regexp.Regexp, matcher *utils.IgnoreMatcher, includePath ...string) (err error) {
 
 	beeLogger.Log.Infof("Excluding relpath prefix: %s", strings.Join(excludePrefix, ":"))
 	beeLogger.Log.Infof("Excluding relpath suffix: %s", strings.Join(excludeSuffix, ":"))
@@ -382,6 +390,7 @@ func packDirectory(output io.Writer, excludePrefix []string, excludeSuffix []str
 		walk.excludePrefix = excludePrefix
 		walk.excludeSuffix = excludeSuffix
 		walk.excludeRegexp = excludeRegexp
+		walk.matcher = matcher
 		wft = walk
 	} else {
 		walk := new(tarWalk)
@@ -401,6 +410,7 @@ func packDirectory(output io.Writer, excludePrefix []string, excludeSuffix []str
 		walk.excludePrefix = excludePrefix
 		walk.excludeSuffix = excludeSuffix
 		walk.excludeRegexp = excludeRegexp
+		walk.matcher = matcher
 		wft = walk
 	}
 
@@ -567,7 +577,9 @@ func packApp(cmd *commands.Command, args []string) int {
 
 	beeLogger.Log.Infof("Writing to output: %s", outputP)
 
-	err = packDirectory(output, exp, exs, exr, tmpdir, thePath)
+	matcher := utils.LoadIgnoreMatcher(thePath, nil, config.Conf.Watch.Exclude, config.Conf.Watch.Include)
+
+	err = packDirectory(output, exp, exs, exr, matcher, tmpdir, thePath)
 	if err != nil {
 		beeLogger.Log.Fatal(err.Error())
 	}
@@ -16,10 +16,9 @@
 package rs
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
 	"time"
 
 	"strings"
@@ -37,6 +36,7 @@ var cmdRs = &commands.Command{
 	Short:     "Run customized scripts",
 	Long: `Run script allows you to run arbitrary commands using Bee.
   Custom commands are provided from the "scripts" object inside bee.json or Beefile.
+  They run with the variables of the .env files added to their environment.
 
   To run a custom command, use: {{"$ bee rs mycmd ARGS" | bold}}
   {{if len .}}
@@ -87,15 +87,14 @@ type customCommand struct {
 
 func (c *customCommand) run() error {
 	beeLogger.Log.Info(colors.GreenBold(fmt.Sprintf("Running '%s'...", c.Name)))
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "darwin", "linux":
-		args := append([]string{c.Command}, c.Args...)
-		cmd = exec.Command("sh", "-c", strings.Join(args, " "))
-	case "windows":
-		args := append([]string{c.Command}, c.Args...)
-		cmd = exec.Command("cmd", "/C", strings.Join(args, " "))
+	args := append([]string{c.Command}, c.Args...)
+	cmd := utils.ShellCommand(context.Background(), strings.Join(args, " "))
+	currpath, _ := os.Getwd()
+	env, err := utils.LoadEnvFiles(currpath, os.Getenv("BEEGO_RUNMODE"))
+	if err != nil {
+		beeLogger.Log.Warnf("Could not load the .env files: %s", err)
 	}
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -0,0 +1,65 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// buildError is a compiler error reported by "go build"
+type buildError struct {
+	Package string `json:"package,omitempty"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+var buildErrorRegExp = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// parseBuildErrors extracts the compiler errors from the output of "go build".
+// Lines which are not errors, such as the "# package" headers, are skipped
+// and indented lines are appended to the message of the previous error.
+func parseBuildErrors(output string) []buildError {
+	var (
+		errs []buildError
+		pkg  string
+	)
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			pkg = strings.TrimSpace(line[2:])
+			continue
+		}
+		if len(errs) > 0 && strings.HasPrefix(line, "\t") {
+			errs[len(errs)-1].Message += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		m := buildErrorRegExp.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		errs = append(errs, buildError{
+			Package: pkg,
+			File:    strings.TrimPrefix(m[1], "./"),
+			Line:    lineNo,
+			Column:  column,
+			Message: m[4],
+		})
+	}
+	return errs
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []buildError
+	}{
+		{"no output", "", nil},
+		{"no error", "go: downloading github.com/cisordeng/beego v1.0.0\n", nil},
+		{
+			"error with a column",
+			"# hello/controllers\n./controllers/default.go:12:2: undefined: fmt\n",
+			[]buildError{{"hello/controllers", "controllers/default.go", 12, 2, "undefined: fmt"}},
+		},
+		{
+			"error without a column",
+			"main.go:7: syntax error: unexpected }\n",
+			[]buildError{{"", "main.go", 7, 0, "syntax error: unexpected }"}},
+		},
+		{
+			"continuation lines",
+			"# hello\n./main.go:9:15: cannot use x (type int) as type string in argument to f:\n\tneed type assertion\n",
+			[]buildError{{"hello", "main.go", 9, 15, "cannot use x (type int) as type string in argument to f:\nneed type assertion"}},
+		},
+		{
+			"several packages",
+			"# hello/models\nmodels/user.go:3:1: expected declaration\n# hello\n./main.go:5:2: imported and not used: \"os\"\n",
+			[]buildError{
+				{"hello/models", "models/user.go", 3, 1, "expected declaration"},
+				{"hello", "main.go", 5, 2, "imported and not used: \"os\""},
+			},
+		},
+		{"indented line before any error", "\tnote: module requires Go 1.13\n", nil},
+	}
+	for _, tt := range tests {
+		if got := parseBuildErrors(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseBuildErrors() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
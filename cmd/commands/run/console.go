@@ -0,0 +1,139 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+	"github.com/peterh/liner"
+)
+
+// consoleKeys describes the keys of the console, in the order they are listed
+var consoleKeys = []struct {
+	key  rune
+	help string
+}{
+	{'r', "to rebuild"},
+	{'t', "to run the tests"},
+	{'c', "to clear the screen"},
+	{'l', "to toggle verbose logging"},
+	{'d', "to generate the docs"},
+	{'s', "to show the build timings"},
+	{'q', "to quit"},
+	{'h', "to show this help"},
+}
+
+// console holds the terminal state while the keys typed in "bee run" are read
+var console struct {
+	sync.Mutex
+	line *liner.State
+}
+
+var shutdownOnce sync.Once
+
+// startConsole reads the keys typed in the terminal and runs their actions.
+// rebuild builds and restarts the application, stop stops it. It returns false
+// if stdin is not a terminal, in which case the console is disabled.
+func startConsole(rebuild, stop func()) bool {
+	if !liner.TerminalSupported() {
+		return false
+	}
+	if _, err := liner.TerminalMode(); err != nil {
+		return false
+	}
+
+	// liner switches the terminal to raw mode, so that the keys are read without Enter
+	console.Lock()
+	console.line = liner.NewLiner()
+	console.Unlock()
+	printConsoleKeys()
+
+	go func() {
+		r := bufio.NewReader(os.Stdin)
+		for {
+			key, _, err := r.ReadRune()
+			if err != nil {
+				return
+			}
+			runConsoleKey(key, rebuild, stop)
+		}
+	}()
+	return true
+}
+
+// runConsoleKey runs the action of a key. Unknown keys are ignored.
+func runConsoleKey(key rune, rebuild, stop func()) {
+	switch key {
+	case 'r', 'R':
+		beeLogger.Log.Info("Rebuilding...")
+		go rebuild()
+	case 't', 'T':
+		go testAffectedPackages(nil)
+	case 'c', 'C':
+		fmt.Fprint(colors.NewColorWriter(os.Stdout), "\033[H\033[2J")
+	case 'l', 'L':
+		on := verbose.Toggle()
+		beeLogger.Log.SetVerbose(on)
+		if on {
+			beeLogger.Log.Info("Verbose logging enabled")
+		} else {
+			beeLogger.Log.Info("Verbose logging disabled")
+		}
+	case 'd', 'D':
+		go generateDocs()
+	case 's', 'S':
+		printTimings()
+	case 'q', 'Q':
+		go shutdown(stop)
+	case 'h', 'H', '?':
+		printConsoleKeys()
+	}
+}
+
+func printConsoleKeys() {
+	keys := ""
+	for _, k := range consoleKeys {
+		keys += fmt.Sprintf(" %s %s,", colors.Bold(string(k.key)), k.help)
+	}
+	beeLogger.Log.Infof("Press%s", keys[:len(keys)-1])
+}
+
+// stopOnSignal stops the application on Ctrl-C
+func stopOnSignal(stop func()) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	shutdown(stop)
+}
+
+// shutdown stops the application, restores the terminal and exits
+func shutdown(stop func()) {
+	shutdownOnce.Do(func() {
+		stop()
+
+		console.Lock()
+		if console.line != nil {
+			console.line.Close()
+		}
+		console.Unlock()
+		os.Exit(0)
+	})
+}
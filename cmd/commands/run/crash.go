@@ -0,0 +1,208 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/utils"
+)
+
+// processExit tracks the exit of a started process
+type processExit struct {
+	done     chan struct{} // Closed once the process exited
+	stopping int32         // Set by stopProcess, the exit is then expected
+}
+
+// exits holds the processExit of the processes waited for by waitExit
+var exits sync.Map
+
+// crashCounter counts the unexpected exits of a process in a row
+type crashCounter struct {
+	sync.Mutex
+	count int
+	gen   uint64 // Incremented when the count is reset, to drop scheduled restarts
+	timer *time.Timer
+}
+
+// crashes counts the crashes of the application
+var crashes crashCounter
+
+// waitExit waits for c in the background.
+// The returned channel is closed once the process exited.
+func waitExit(c *exec.Cmd) <-chan struct{} {
+	return exitOf(c).done
+}
+
+// exitOf returns the processExit of c, waiting for c if nobody does yet
+func exitOf(c *exec.Cmd) *processExit {
+	pe := &processExit{done: make(chan struct{})}
+	if actual, loaded := exits.LoadOrStore(c, pe); loaded {
+		return actual.(*processExit)
+	}
+	go func() {
+		c.Wait()
+		close(pe.done)
+	}()
+	return pe
+}
+
+// watchAppCrash restarts the application if it crashes. The restart is
+// dropped if a build or a restart replaced the process in the meantime.
+func watchAppCrash(c *exec.Cmd, appname string, tail *tailWriter) {
+	watchCrash(c, appname, tail, &crashes, func() {
+		state.Lock()
+		defer state.Unlock()
+		if cmd != c {
+			return
+		}
+		Start(appname)
+	})
+}
+
+// watchCrash waits for the process to exit and calls restart with an
+// exponential backoff if it exited on its own. Once the process crashed
+// too many times in a row, it is only started again by the next build.
+// A process which stayed up longer than the longest backoff starts a new row.
+func watchCrash(c *exec.Cmd, name string, tail *tailWriter, counter *crashCounter, restart func()) {
+	started := time.Now()
+	pe := exitOf(c)
+	<-pe.done
+	exits.Delete(c)
+	if atomic.LoadInt32(&pe.stopping) == 1 {
+		return
+	}
+
+	beeLogger.Log.Errorf("'%s' exited unexpectedly: %s", name, c.ProcessState)
+	lines := tail.Lines()
+	for _, line := range lines {
+		beeLogger.Log.Errorf("|> %s", line)
+	}
+
+	conf := config.Conf.Crash
+	counter.Lock()
+	defer counter.Unlock()
+	if time.Since(started) >= stableUptime(conf.Backoff, conf.MaxRestarts) {
+		counter.count = 0
+	}
+	counter.count++
+
+	if counter.count > conf.MaxRestarts {
+		utils.Notify(strings.Join(lines, "\n"), "Application Crashed")
+		beeLogger.Log.Warnf("'%s' crashed %d times in a row, waiting for changes...", name, counter.count)
+		return
+	}
+
+	delay := time.Duration(conf.Backoff) * time.Millisecond << uint(counter.count-1)
+	beeLogger.Log.Infof("Restarting '%s' in %s (%d/%d)...", name, delay, counter.count, conf.MaxRestarts)
+	gen := counter.gen
+	counter.timer = time.AfterFunc(delay, func() {
+		// The counter is not held while restarting, the
+		// restarts take other locks before resetting it
+		counter.Lock()
+		current := counter.gen == gen
+		counter.Unlock()
+		if current {
+			restart()
+		}
+	})
+}
+
+// stableUptime returns how long a process must stay up for its crashes not to
+// count as a row anymore: the backoff before the last restart
+func stableUptime(backoff, maxRestarts int) time.Duration {
+	if maxRestarts < 1 {
+		maxRestarts = 1
+	}
+	return time.Duration(backoff) * time.Millisecond << uint(maxRestarts-1)
+}
+
+// reset cancels the scheduled restart, if any, and resets the count
+// of crashes. It is called when a new build of the process is started.
+func (cc *crashCounter) reset() {
+	cc.Lock()
+	defer cc.Unlock()
+	if cc.timer != nil {
+		cc.timer.Stop()
+	}
+	cc.count = 0
+	cc.gen++
+}
+
+// maxTailLine is the number of bytes kept at the end of a line written to a tailWriter
+const maxTailLine = 4096
+
+// tailWriter keeps the last lines written to it
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newTailWriter(max int) *tailWriter {
+	if max < 0 {
+		max = 0
+	}
+	return &tailWriter{max: max}
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial = append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := t.partial[:i]
+		if len(line) > maxTailLine {
+			line = line[len(line)-maxTailLine:]
+		}
+		t.lines = append(t.lines, strings.TrimRight(string(line), "\r"))
+		t.partial = t.partial[i+1:]
+	}
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+	// Keep the end of an output without newlines, like a progress bar
+	switch {
+	case t.max == 0:
+		t.partial = nil
+	case len(t.partial) > maxTailLine:
+		t.partial = append([]byte(nil), t.partial[len(t.partial)-maxTailLine:]...)
+	}
+	return len(p), nil
+}
+
+// Lines returns the last lines, including an unterminated one
+func (t *tailWriter) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string(nil), t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, string(t.partial))
+	}
+	return lines
+}
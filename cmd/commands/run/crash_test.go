@@ -0,0 +1,120 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+)
+
+func TestTailWriter(t *testing.T) {
+	long := strings.Repeat("x", maxTailLine+10)
+	tests := []struct {
+		max    int
+		writes []string
+		want   []string
+	}{
+		{3, []string{"a\nb\n"}, []string{"a", "b"}},
+		{3, []string{"a\nb", "c\r\nd"}, []string{"a", "bc", "d"}},
+		{2, []string{"a\nb\nc\nd\n"}, []string{"c", "d"}},
+		{2, []string{"a\n", long}, []string{"a", long[10:]}},
+		{2, []string{long[:maxTailLine], long[maxTailLine:] + "\n"}, []string{long[10:]}},
+		{2, []string{long + "\nb\n"}, []string{long[10:], "b"}},
+		{0, []string{"a\nb"}, nil},
+		{-1, []string{"a\n"}, nil},
+	}
+	for _, tt := range tests {
+		tail := newTailWriter(tt.max)
+		for _, w := range tt.writes {
+			if n, err := tail.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("Write(%q) = %d, %v", w, n, err)
+			}
+		}
+		if got := tail.Lines(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("max %d, writes %q: Lines() = %q, want %q", tt.max, tt.writes, got, tt.want)
+		}
+	}
+}
+
+func TestStableUptime(t *testing.T) {
+	tests := []struct {
+		backoff, maxRestarts int
+		want                 time.Duration
+	}{
+		{1000, 5, 16 * time.Second},
+		{1000, 1, time.Second},
+		{500, 0, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := stableUptime(tt.backoff, tt.maxRestarts); got != tt.want {
+			t.Errorf("stableUptime(%d, %d) = %s, want %s", tt.backoff, tt.maxRestarts, got, tt.want)
+		}
+	}
+}
+
+func TestWatchCrash(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	conf := config.Conf
+	defer func() { config.Conf = conf }()
+	// The process is stable once it stayed up for 200ms
+	config.Conf.Crash.Backoff = 100
+	config.Conf.Crash.MaxRestarts = 2
+	config.Conf.EnableNotification = false
+
+	tests := []struct {
+		name      string
+		script    string
+		count     int // Crashes in a row before this one
+		wantCount int
+		restarted bool
+	}{
+		{"first crash", "exit 1", 0, 1, true},
+		{"crash in a row", "exit 1", 1, 2, true},
+		{"too many crashes", "exit 1", 2, 3, false},
+		{"crash after a stable period", "sleep 0.3; exit 1", 2, 1, true},
+	}
+	for _, tt := range tests {
+		c := exec.Command("sh", "-c", tt.script)
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		counter := &crashCounter{count: tt.count}
+		restarted := make(chan struct{}, 1)
+		watchCrash(c, tt.name, newTailWriter(1), counter, func() { restarted <- struct{}{} })
+
+		counter.Lock()
+		count := counter.count
+		counter.Unlock()
+		if count != tt.wantCount {
+			t.Errorf("%s: count = %d, want %d", tt.name, count, tt.wantCount)
+		}
+		select {
+		case <-restarted:
+			if !tt.restarted {
+				t.Errorf("%s: restarted, want no restart", tt.name)
+			}
+		case <-time.After(time.Second):
+			if tt.restarted {
+				t.Errorf("%s: not restarted", tt.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	path "path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+)
+
+// debouncer collects the changed files and runs its action once no more
+// changes arrived during the quiet window of 'build_delay' milliseconds.
+// It is shared by the application, the supervised processes and the watchers.
+type debouncer struct {
+	mu      sync.Mutex
+	files   map[string]struct{}
+	flagged bool // Whether one of the collected files was flagged
+	timer   *time.Timer
+	seq     uint64 // Incremented on each change, so that only the latest timer runs the action
+	stopped bool
+
+	action func(files []string, flagged bool)
+}
+
+// newDebouncer returns a debouncer calling action with the sorted changed files,
+// and with whether one of them was flagged when it was scheduled
+func newDebouncer(action func(files []string, flagged bool)) *debouncer {
+	return &debouncer{files: make(map[string]struct{}), action: action}
+}
+
+// schedule records a changed file and (re)starts the quiet window
+func (d *debouncer) schedule(name string, flagged bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+	d.files[name] = struct{}{}
+	d.flagged = d.flagged || flagged
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.seq++
+	seq := d.seq
+	delay := time.Duration(config.Conf.BuildDelay) * time.Millisecond
+	d.timer = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		// A timer which could not be stopped in time leaves the files to the latest one
+		if d.stopped || d.seq != seq {
+			d.mu.Unlock()
+			return
+		}
+		files := make([]string, 0, len(d.files))
+		for name := range d.files {
+			files = append(files, name)
+		}
+		flagged := d.flagged
+		d.files = make(map[string]struct{})
+		d.flagged = false
+		d.mu.Unlock()
+
+		sort.Strings(files)
+		d.action(files, flagged)
+	})
+}
+
+// stop drops the pending changes. The action is not run anymore.
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.files = make(map[string]struct{})
+}
+
+// relPath returns the path of a changed file relative to the application
+// directory, to be logged, or the path itself if it is outside of it
+func relPath(name string) string {
+	if rel, err := path.Rel(currpath, name); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return name
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+)
+
+func TestDebouncer(t *testing.T) {
+	defer func(delay int) { config.Conf.BuildDelay = delay }(config.Conf.BuildDelay)
+	config.Conf.BuildDelay = 20
+
+	type run struct {
+		files   []string
+		flagged bool
+	}
+	tests := []struct {
+		name    string
+		changes []string // Flagged if prefixed with '!'
+		stop    bool
+		want    []run
+	}{
+		{"single change", []string{"a.go"}, false, []run{{[]string{"a.go"}, false}}},
+		{"changes collected once", []string{"b.go", "a.go", "b.go"}, false, []run{{[]string{"a.go", "b.go"}, false}}},
+		{"flagged change", []string{"a.go", "!main.go"}, false, []run{{[]string{"a.go", "main.go"}, true}}},
+		{"stopped", []string{"a.go"}, true, nil},
+	}
+	for _, tt := range tests {
+		runs := make(chan run, 10)
+		d := newDebouncer(func(files []string, flagged bool) { runs <- run{files, flagged} })
+		for _, name := range tt.changes {
+			flagged := name[0] == '!'
+			if flagged {
+				name = name[1:]
+			}
+			d.schedule(name, flagged)
+		}
+		if tt.stop {
+			d.stop()
+			d.schedule("b.go", false)
+		}
+
+		var got []run
+		timeout := time.After(200 * time.Millisecond)
+	wait:
+		for {
+			select {
+			case r := <-runs:
+				got = append(got, r)
+			case <-timeout:
+				break wait
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: runs = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+)
+
+// buildEvent is written as a JSON line for each build when -events is set.
+// Its type is either "build_started", "build_succeeded" or "build_failed".
+type buildEvent struct {
+	Type     string          `json:"type"`
+	Time     time.Time       `json:"time"`
+	App      string          `json:"app"`
+	Packages []packageErrors `json:"packages,omitempty"`
+	Output   string          `json:"output,omitempty"` // Output of "go build" when no error could be parsed
+}
+
+// packageErrors holds the compiler errors of a package
+type packageErrors struct {
+	Package string       `json:"package"`
+	Errors  []buildError `json:"errors"`
+}
+
+// eventSink receives the build events: a file, and the clients of the HTTP endpoint
+var eventSink struct {
+	sync.Mutex
+	enabled bool
+	file    *os.File
+	clients map[chan []byte]struct{}
+	last    []byte // Last event, sent first to new clients
+}
+
+// startEvents writes the build events to target, which is either the
+// address of an HTTP endpoint to serve them on (e.g. ":7070") or a file
+func startEvents(target string) {
+	eventSink.Lock()
+	defer eventSink.Unlock()
+
+	if isListenAddress(target) {
+		eventSink.clients = make(map[chan []byte]struct{})
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", handleEventsRequest)
+		go func() {
+			if err := http.ListenAndServe(target, mux); err != nil {
+				beeLogger.Log.Errorf("Failed to start the build events server: %s", err)
+			}
+		}()
+		beeLogger.Log.Infof("Serving the build events at http://%s", target)
+	} else {
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			beeLogger.Log.Fatalf("Failed to open the build events file: %s", err)
+		}
+		eventSink.file = f
+		beeLogger.Log.Infof("Writing the build events to '%s'", target)
+	}
+	eventSink.enabled = true
+}
+
+// isListenAddress reports whether target is a host:port address rather than a file
+func isListenAddress(target string) bool {
+	if strings.ContainsAny(target, `/\`) {
+		return false
+	}
+	_, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return false
+	}
+	_, err = strconv.Atoi(port)
+	return err == nil
+}
+
+// handleEventsRequest streams the build events as JSON lines,
+// starting with the last one, until the client disconnects
+func handleEventsRequest(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, 64)
+	eventSink.Lock()
+	eventSink.clients[ch] = struct{}{}
+	last := eventSink.last
+	eventSink.Unlock()
+	defer func() {
+		eventSink.Lock()
+		delete(eventSink.clients, ch)
+		eventSink.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	if last != nil {
+		w.Write(last)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case line := <-ch:
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// emitEvent writes the event to the file and sends it to the clients
+func emitEvent(e buildEvent) {
+	eventSink.Lock()
+	defer eventSink.Unlock()
+
+	if !eventSink.enabled {
+		return
+	}
+	e.Time = time.Now()
+	line, err := json.Marshal(e)
+	if err != nil {
+		beeLogger.Log.Errorf("Failed to encode the build event: %s", err)
+		return
+	}
+	line = append(line, '\n')
+	eventSink.last = line
+
+	if eventSink.file != nil {
+		if _, err := eventSink.file.Write(line); err != nil {
+			beeLogger.Log.Errorf("Failed to write the build event: %s", err)
+		}
+	}
+	for ch := range eventSink.clients {
+		// Slow clients miss events rather than blocking the builds
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// reportBuildStarted reports the start of a build of app
+func reportBuildStarted(app string) {
+	emitEvent(buildEvent{Type: "build_started", App: app})
+}
+
+// reportBuildSucceeded reports a successful build of app to the browsers and to the events
+func reportBuildSucceeded(app string) {
+	if config.Conf.EnableReload {
+		sendBuildSucceeded()
+	}
+	emitEvent(buildEvent{Type: "build_succeeded", App: app})
+}
+
+// reportBuildFailed prints the compiler errors of a failed build of app,
+// and reports them to the browsers and to the events
+func reportBuildFailed(app, output string) {
+	pkgs := groupBuildErrors(parseBuildErrors(output))
+	printBuildErrors(app, pkgs, output)
+	if config.Conf.EnableReload {
+		sendBuildFailed(output)
+	}
+
+	e := buildEvent{Type: "build_failed", App: app, Packages: pkgs}
+	if len(pkgs) == 0 {
+		e.Output = output
+	}
+	emitEvent(e)
+}
+
+// groupBuildErrors groups the errors by package, in the order of the output
+func groupBuildErrors(errs []buildError) []packageErrors {
+	var pkgs []packageErrors
+	index := make(map[string]int)
+	for _, e := range errs {
+		i, ok := index[e.Package]
+		if !ok {
+			i = len(pkgs)
+			index[e.Package] = i
+			pkgs = append(pkgs, packageErrors{Package: e.Package})
+		}
+		// The package is already given by the group
+		e.Package = ""
+		pkgs[i].Errors = append(pkgs[i].Errors, e)
+	}
+	return pkgs
+}
+
+// printBuildErrors prints the errors of each package with their position,
+// or the raw output if it could not be parsed
+func printBuildErrors(app string, pkgs []packageErrors, output string) {
+	if len(pkgs) == 0 {
+		beeLogger.Log.Errorf("Failed to build '%s': %s", app, output)
+		return
+	}
+
+	count := 0
+	for _, pkg := range pkgs {
+		count += len(pkg.Errors)
+	}
+	beeLogger.Log.Errorf("Failed to build '%s': %d error(s)", app, count)
+
+	w := colors.NewColorWriter(os.Stderr)
+	for _, pkg := range pkgs {
+		if pkg.Package != "" {
+			fmt.Fprintln(w, colors.Bold(pkg.Package))
+		}
+		for _, e := range pkg.Errors {
+			pos := fmt.Sprintf("%s:%d", e.File, e.Line)
+			if e.Column > 0 {
+				pos += fmt.Sprintf(":%d", e.Column)
+			}
+			lines := strings.Split(e.Message, "\n")
+			fmt.Fprintf(w, "  %s %s\n", colors.YellowBold(pos), colors.Red(lines[0]))
+			for _, line := range lines[1:] {
+				fmt.Fprintf(w, "      %s\n", line)
+			}
+		}
+	}
+}
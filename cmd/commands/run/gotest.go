@@ -0,0 +1,184 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	path "path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/utils"
+)
+
+// tests tracks the test run in progress so that a newer one can cancel it
+var tests struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// listedPackage is a package of the application as reported by "go list"
+type listedPackage struct {
+	importPath string
+	dir        string
+	imports    []string // Direct and indirect dependencies, including those of the tests
+}
+
+// testAffectedPackages runs the tests of the packages containing the changed
+// files and of the packages importing them, or of all the packages if changed
+// is nil. The result is shown in the terminal and through a notification.
+// Starting a new run cancels this one.
+func testAffectedPackages(changed []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tests.Lock()
+	if tests.cancel != nil {
+		tests.cancel()
+	}
+	tests.cancel = cancel
+	tests.Unlock()
+
+	pkgs, err := listPackages(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			beeLogger.Log.Errorf("Failed to list the packages to test: %s", err)
+		}
+		return
+	}
+	var affected []string
+	if changed == nil {
+		for _, pkg := range pkgs {
+			affected = append(affected, pkg.importPath)
+		}
+	} else {
+		affected = affectedPackages(pkgs, changed)
+	}
+	if len(affected) == 0 {
+		beeLogger.Log.Info("No package to test for these changes")
+		return
+	}
+
+	beeLogger.Log.Infof("Testing %s...", strings.Join(affected, ", "))
+	args := []string{"test"}
+	if buildTags != "" {
+		args = append(args, "-tags", buildTags)
+	}
+	args = append(args, affected...)
+
+	var out bytes.Buffer
+	tcmd := exec.CommandContext(ctx, "go", args...)
+	tcmd.Dir = currpath
+	tcmd.Stdout = &out
+	tcmd.Stderr = &out
+	err = tcmd.Run()
+	if ctx.Err() != nil {
+		return
+	}
+
+	passed, failed := summarizeTests(out.String())
+	if err == nil {
+		msg := fmt.Sprintf("%d package(s) passed", len(passed))
+		utils.Notify(msg, "Tests Passed")
+		beeLogger.Log.Successf("Tests passed: %s", msg)
+		return
+	}
+
+	os.Stdout.Write(out.Bytes())
+	msg := fmt.Sprintf("%d package(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	if len(failed) == 0 {
+		msg = err.Error()
+	}
+	utils.Notify(msg, "Tests Failed")
+	beeLogger.Log.Errorf("Tests failed: %s", msg)
+}
+
+// listPackages lists the packages of the application with their dependencies
+func listPackages(ctx context.Context) ([]listedPackage, error) {
+	format := `{{.ImportPath}}|{{.Dir}}|{{join .Deps " "}} {{join .TestImports " "}} {{join .XTestImports " "}}`
+	lcmd := exec.CommandContext(ctx, "go", "list", "-e", "-f", format, "./...")
+	lcmd.Dir = currpath
+	var stderr bytes.Buffer
+	lcmd.Stderr = &stderr
+	out, err := lcmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []listedPackage
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "|", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		pkgs = append(pkgs, listedPackage{
+			importPath: parts[0],
+			dir:        parts[1],
+			imports:    strings.Fields(parts[2]),
+		})
+	}
+	return pkgs, nil
+}
+
+// affectedPackages returns the import paths of the packages containing
+// one of the changed files, and of the packages depending on them
+func affectedPackages(pkgs []listedPackage, changed []string) []string {
+	changedPkgs := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, file := range changed {
+			if path.Dir(file) == pkg.dir {
+				changedPkgs[pkg.importPath] = true
+			}
+		}
+	}
+
+	var affected []string
+	for _, pkg := range pkgs {
+		isAffected := changedPkgs[pkg.importPath]
+		for _, imp := range pkg.imports {
+			if changedPkgs[imp] {
+				isAffected = true
+				break
+			}
+		}
+		if isAffected {
+			affected = append(affected, pkg.importPath)
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+// summarizeTests returns the packages reported as passing and failing by "go test"
+func summarizeTests(output string) (passed, failed []string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "ok":
+			passed = append(passed, fields[1])
+		case "FAIL":
+			failed = append(failed, fields[1])
+		}
+	}
+	return passed, failed
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	root := filepath.Join("/go", "src", "hello")
+	pkgs := []listedPackage{
+		{"hello", root, []string{"hello/controllers", "hello/models", "hello/routers"}},
+		{"hello/controllers", filepath.Join(root, "controllers"), []string{"hello/models"}},
+		{"hello/models", filepath.Join(root, "models"), nil},
+		{"hello/routers", filepath.Join(root, "routers"), []string{"hello/controllers", "hello/models"}},
+		{"hello/tools", filepath.Join(root, "tools"), []string{"fmt"}},
+	}
+
+	tests := []struct {
+		changed []string
+		want    []string
+	}{
+		{nil, nil},
+		{[]string{filepath.Join(root, "README.md")}, []string{"hello"}},
+		{[]string{filepath.Join(root, "tools", "gen.go")}, []string{"hello/tools"}},
+		{[]string{filepath.Join(root, "controllers", "user.go")}, []string{"hello", "hello/controllers", "hello/routers"}},
+		{[]string{filepath.Join(root, "models", "user.go")}, []string{"hello", "hello/controllers", "hello/models", "hello/routers"}},
+		{[]string{filepath.Join(root, "tools", "gen.go"), filepath.Join(root, "routers", "router.go")}, []string{"hello", "hello/routers", "hello/tools"}},
+		{[]string{filepath.Join(root, "views", "index.tpl")}, nil},
+	}
+	for _, tt := range tests {
+		if got := affectedPackages(pkgs, tt.changed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("affectedPackages(%q) = %q, want %q", tt.changed, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeTests(t *testing.T) {
+	tests := []struct {
+		output         string
+		passed, failed []string
+	}{
+		{"", nil, nil},
+		{"ok  \thello/models\t0.012s\n?   \thello/routers\t[no test files]\n", []string{"hello/models"}, nil},
+		{
+			"--- FAIL: TestUser (0.00s)\n    user_test.go:12: got 1, want 2\nFAIL\nFAIL\thello/models\t0.010s\nok  \thello/controllers\t(cached)\n",
+			[]string{"hello/controllers"}, []string{"hello/models"},
+		},
+		{"# hello/models\nmodels/user.go:3:1: expected declaration\nFAIL\thello/models [build failed]\n", nil, []string{"hello/models"}},
+	}
+	for _, tt := range tests {
+		passed, failed := summarizeTests(tt.output)
+		if !reflect.DeepEqual(passed, tt.passed) || !reflect.DeepEqual(failed, tt.failed) {
+			t.Errorf("summarizeTests(%q) = %q, %q, want %q, %q", tt.output, passed, failed, tt.passed, tt.failed)
+		}
+	}
+}
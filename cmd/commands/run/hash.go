@@ -0,0 +1,132 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"crypto/sha1"
+	"io"
+	"io/ioutil"
+	"os"
+	path "path/filepath"
+	"sync"
+	"time"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+)
+
+// fileHashes holds the hash of the content of the watched files
+// of all the directories collected by readAppDirectories
+var fileHashes = struct {
+	sync.Mutex
+	sums map[string]fileHash
+}{
+	sums: make(map[string]fileHash),
+}
+
+// fileHash is the hash of the content of a file, and the modification time
+// and size of the file when it was hashed. As long as they are the same, the
+// file is not hashed again, unless it was modified shortly before it was hashed:
+// a change made within the resolution of the modification time can be missed.
+type fileHash struct {
+	stat   fileStat
+	sum    [sha1.Size]byte
+	hashed int64 // Time of the hash, in nanoseconds
+}
+
+// racyWindow is how long before it was hashed a file must have been modified
+// for its modification time and size to tell that it did not change since
+const racyWindow = int64(time.Second)
+
+// newFileHash hashes the file, whose stat is fi
+func newFileHash(name string, fi os.FileInfo) (fileHash, error) {
+	hashed := time.Now().UnixNano()
+	sum, err := hashFile(name)
+	return fileHash{stat: statOf(fi), sum: sum, hashed: hashed}, err
+}
+
+// unchangedSince reports whether the file, whose stat is fi, certainly
+// did not change since it was hashed, without hashing it again
+func (h fileHash) unchangedSince(fi os.FileInfo) bool {
+	return h.stat == statOf(fi) && h.hashed-h.stat.modTime > racyWindow
+}
+
+// indexFileHashes records the hash of the files of the directories selected by filter
+func indexFileHashes(dirs []string, filter func(string) bool) {
+	fileHashes.Lock()
+	defer fileHashes.Unlock()
+
+	for _, dir := range dirs {
+		fileInfos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fileInfo := range fileInfos {
+			name := path.Join(dir, fileInfo.Name())
+			if fileInfo.IsDir() || !filter(name) {
+				continue
+			}
+			if h, err := newFileHash(name, fileInfo); err == nil {
+				fileHashes.sums[name] = h
+			}
+		}
+	}
+	beeLogger.Log.Hintf("Indexed the content of %d files", len(fileHashes.sums))
+}
+
+// isHashedFile selects the files of the application whose content is indexed
+func isHashedFile(name string) bool {
+	return !shouldIgnoreFile(name) && (shouldWatchFileWithExtension(name) || ifRestartFile(name))
+}
+
+// contentChanged reports whether the content of the file changed since it was last
+// seen, and records its new hash. The file is only hashed again if its modification
+// time or its size changed. A file which cannot be read, e.g. because it was
+// removed, is considered changed.
+func contentChanged(name string) bool {
+	fileHashes.Lock()
+	defer fileHashes.Unlock()
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		delete(fileHashes.sums, name)
+		return true
+	}
+	prev, known := fileHashes.sums[name]
+	if known && prev.unchangedSince(fi) {
+		return false
+	}
+
+	h, err := newFileHash(name, fi)
+	if err != nil {
+		delete(fileHashes.sums, name)
+		return true
+	}
+	fileHashes.sums[name] = h
+	return !known || prev.sum != h.sum
+}
+
+func hashFile(name string) (sum [sha1.Size]byte, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
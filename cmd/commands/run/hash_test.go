@@ -0,0 +1,67 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentChanged(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "main.go")
+	old := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		action  string
+		content string // Written if not empty
+		modTime time.Time
+		want    bool
+	}{
+		{"first event", "package main", old, true},
+		{"touch", "", time.Now(), false},
+		{"same content saved again", "package main", time.Now(), false},
+		// Same size and modification time, within the resolution of the filesystem
+		{"edit in the same tick", "package mian", time.Now(), true},
+		{"edit", "package main // x", old.Add(time.Minute), true},
+		{"no change", "", old.Add(time.Minute), false},
+		{"not hashed again when modified long before its hash", "package main // y", old.Add(time.Minute), false},
+		{"removal", "-", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		switch tt.content {
+		case "":
+		case "-":
+			os.Remove(name)
+		default:
+			if err := ioutil.WriteFile(name, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !tt.modTime.IsZero() {
+			if tt.action == "edit in the same tick" {
+				// Keep the modification time of the previous save
+				fi, _ := os.Stat(name)
+				tt.modTime = fi.ModTime()
+			}
+			os.Chtimes(name, tt.modTime, tt.modTime)
+		}
+		if got := contentChanged(name); got != tt.want {
+			t.Errorf("%s: contentChanged() = %v, want %v", tt.action, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/utils"
+)
+
+// healthCheckEnabled reports whether a readiness check is configured
+func healthCheckEnabled() bool {
+	return config.Conf.HealthCheck.URL != "" || config.Conf.HealthCheck.TCP != ""
+}
+
+// nextBinaryName returns the name the new build is written to
+// while the previous one keeps running
+func nextBinaryName(appName string) string {
+	if strings.HasSuffix(appName, ".exe") {
+		return strings.TrimSuffix(appName, ".exe") + "-next.exe"
+	}
+	return appName + "-next"
+}
+
+// prevBinaryName returns the name the previous build is kept under
+// while the new one is checked, to start it again if the check fails
+func prevBinaryName(appName string) string {
+	if strings.HasSuffix(appName, ".exe") {
+		return strings.TrimSuffix(appName, ".exe") + "-prev.exe"
+	}
+	return appName + "-prev"
+}
+
+// RestartWhenHealthy replaces the running application with the binary next,
+// only once next passes the readiness check. If the check fails the previous
+// build keeps serving and the startup log of next is shown.
+// The binaries are only renamed while none of them is running, which
+// Windows would not allow. It returns true if the new build was started.
+func RestartWhenHealthy(appName, next string) bool {
+	check := config.Conf.HealthCheck
+	if check.PortEnv != "" {
+		return restartAfterSideCheck(appName, next)
+	}
+	if check.SidePort > 0 {
+		beeLogger.Log.Warnf("The side port %d is not used without a 'port_env' to pass it to '%s'", check.SidePort, next)
+	}
+	return restartWithHandoff(appName, next)
+}
+
+// restartAfterSideCheck starts next on the side port while the application
+// keeps serving, waits for next to be ready and only then restarts the
+// application with it. Without a configured side port, a free one is used.
+func restartAfterSideCheck(appName, next string) bool {
+	check := config.Conf.HealthCheck
+	port := check.SidePort
+	if port == 0 {
+		var err error
+		if port, err = freePort(); err != nil {
+			beeLogger.Log.Errorf("Could not find a free side port: %s", err)
+			return false
+		}
+	}
+
+	beeLogger.Log.Infof("Checking '%s' on side port %d...", next, port)
+	log := new(startupLog)
+	c := newAppCmd("./" + next)
+	c.Env = append(c.Env, fmt.Sprintf("%s=%d", check.PortEnv, port))
+	c.Stdout = log.writer(os.Stdout)
+	c.Stderr = log.writer(os.Stderr)
+	if err := c.Start(); err != nil {
+		beeLogger.Log.Errorf("Failed to start '%s': %s", next, err)
+		return false
+	}
+	exited := waitExit(c)
+
+	err := waitHealthy(port, exited)
+	stopProcess(c)
+	if err != nil {
+		reportUnhealthy(next, err, log)
+		os.Remove(next)
+		return false
+	}
+
+	Kill()
+	if err := os.Rename(next, appName); err != nil {
+		beeLogger.Log.Errorf("Failed to replace '%s': %s", appName, err)
+		Start(appName)
+		return false
+	}
+	Start(appName)
+	return true
+}
+
+// freePort returns a TCP port which is free on the local host
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// restartWithHandoff stops the running application, which holds the port
+// next needs, starts next in its place and starts the previous build again
+// if next does not become ready.
+func restartWithHandoff(appName, next string) bool {
+	Kill()
+
+	// The previous build is also kept by a link since "go install" removes appName
+	prev := prevBinaryName(appName)
+	if utils.IsExist(appName) {
+		if err := os.Rename(appName, prev); err != nil {
+			beeLogger.Log.Errorf("Failed to keep the previous build of '%s': %s", appName, err)
+			Start(appName)
+			return false
+		}
+	}
+	hasPrev := utils.IsExist(prev)
+	if err := os.Rename(next, appName); err != nil {
+		beeLogger.Log.Errorf("Failed to replace '%s': %s", appName, err)
+		rollback(appName, prev, hasPrev)
+		return false
+	}
+
+	beeLogger.Log.Infof("Starting '%s'...", appName)
+	log := new(startupLog)
+	tail := newTailWriter(config.Conf.Crash.LogLines)
+	c := newAppCmd("./" + appName)
+	c.Stdout = log.writer(os.Stdout)
+	c.Stderr = io.MultiWriter(log.writer(os.Stderr), tail)
+	if err := c.Start(); err != nil {
+		beeLogger.Log.Errorf("Failed to start '%s': %s", appName, err)
+		rollback(appName, prev, hasPrev)
+		return false
+	}
+	exited := waitExit(c)
+
+	if err := waitHealthy(0, exited); err != nil {
+		stopProcess(c)
+		reportUnhealthy(appName, err, log)
+		rollback(appName, prev, hasPrev)
+		return false
+	}
+
+	cmd = c
+	go watchAppCrash(c, appName, tail)
+	log.release()
+	resumeRequests()
+	os.Remove(prev)
+	os.Link(appName, prev)
+	beeLogger.Log.Successf("'%s' is running...", appName)
+	return true
+}
+
+// rollback puts the previous build back in place of the failed one,
+// and starts it again, if there is one
+func rollback(appName, prev string, hasPrev bool) {
+	if !hasPrev {
+		os.Remove(appName)
+		resumeRequests()
+		return
+	}
+	os.Remove(appName)
+	if err := os.Link(prev, appName); err != nil {
+		if err := os.Rename(prev, appName); err != nil {
+			beeLogger.Log.Errorf("Failed to put the previous build of '%s' back: %s", appName, err)
+			resumeRequests()
+			return
+		}
+	}
+	beeLogger.Log.Warnf("Starting the previous build of '%s' again", appName)
+	Start(appName)
+}
+
+// waitHealthy polls the readiness check until it passes, the process
+// exits or the timeout elapses. A non-zero port overrides the port
+// of the configured check.
+func waitHealthy(port int, exited <-chan struct{}) error {
+	check := config.Conf.HealthCheck
+	timeout := time.Duration(check.Timeout) * time.Millisecond
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		err := probe(check.URL, check.TCP, port)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("the process exited before being ready")
+		case <-deadline:
+			return fmt.Errorf("not ready after %s: %s", timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe runs the HTTP or TCP readiness check once
+func probe(rawURL, addr string, port int) error {
+	if rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		if port > 0 {
+			u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+		}
+		client := http.Client{Timeout: time.Second}
+		resp, err := client.Get(u.String())
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s answered with status %d", u, resp.StatusCode)
+		}
+		return nil
+	}
+
+	if port > 0 {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// reportUnhealthy shows why the new build was rejected along with its startup log
+func reportUnhealthy(next string, err error, log *startupLog) {
+	utils.Notify(err.Error(), "Health Check Failed")
+	beeLogger.Log.Errorf("'%s' failed the health check: %s", next, err)
+	if out := log.String(); out != "" {
+		beeLogger.Log.Error("Startup log of the failed build:")
+		for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+			beeLogger.Log.Errorf("|> %s", line)
+		}
+	}
+}
+
+// startupLog holds the output of a starting process until the process
+// is known to be healthy. Each chunk remembers the stream it was written to.
+type startupLog struct {
+	mu       sync.Mutex
+	chunks   []logChunk
+	released bool
+}
+
+type logChunk struct {
+	w io.Writer
+	p []byte
+}
+
+type startupLogWriter struct {
+	log *startupLog
+	w   io.Writer
+}
+
+// writer returns a writer holding its output in the log until released to w
+func (l *startupLog) writer(w io.Writer) io.Writer {
+	return &startupLogWriter{log: l, w: w}
+}
+
+func (lw *startupLogWriter) Write(p []byte) (int, error) {
+	l := lw.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return lw.w.Write(p)
+	}
+	l.chunks = append(l.chunks, logChunk{w: lw.w, p: append([]byte(nil), p...)})
+	return len(p), nil
+}
+
+// release writes the held output and forwards any further output directly
+func (l *startupLog) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.chunks {
+		c.w.Write(c.p)
+	}
+	l.chunks = nil
+	l.released = true
+}
+
+// String returns the held output of all the streams
+func (l *startupLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var buf bytes.Buffer
+	for _, c := range l.chunks {
+		buf.Write(c.p)
+	}
+	return buf.String()
+}
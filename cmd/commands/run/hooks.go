@@ -0,0 +1,55 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+	"github.com/cisordeng/bee/utils"
+)
+
+// runHooks runs the hooks of a stage one after the other. A hook is either
+// the name of a script of the Beefile or an inline command. The output of the
+// hooks is prefixed by the stage. It stops at the first failing hook.
+func runHooks(ctx context.Context, stage string, hooks []string) error {
+	for _, hook := range hooks {
+		command := hook
+		if script, ok := config.Conf.Scripts[hook]; ok {
+			command = script
+		}
+
+		beeLogger.Log.Infof("Running %s hook '%s'...", stage, hook)
+		prefix := colors.YellowBold(stage+" |") + " "
+		stdout := &prefixWriter{w: colors.NewColorWriter(os.Stdout), prefix: prefix}
+		stderr := &prefixWriter{w: colors.NewColorWriter(os.Stderr), prefix: prefix}
+
+		c := utils.ShellCommand(ctx, command)
+		c.Dir = currpath
+		c.Env = appEnv()
+		c.Stdout = stdout
+		c.Stderr = stderr
+		err := c.Run()
+		stdout.flush()
+		stderr.flush()
+		if err != nil {
+			return fmt.Errorf("%s hook '%s' failed: %s", stage, hook, err)
+		}
+	}
+	return nil
+}
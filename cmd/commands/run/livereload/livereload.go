@@ -0,0 +1,24 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package livereload
+
+// JsClient is the script connecting the browser to the reload server of "bee run".
+// It reloads the page after a rebuild and shows the compiler errors of a failed
+// build in a dismissable overlay, cleared by the next successful build.
+//
+// It connects with the protocol version 2, so that the reload server sends it
+// JSON messages, one per line. The scripts which connect without a version only
+// get the payload of the reloads, and reload the page on each message.
+const JsClient = `(function(){var o=null;function h(){o&&o.parentNode&&o.parentNode.removeChild(o);o=null}function e(s){return String(s).replace(/[&<>"]/g,function(c){return{"&":"&amp;","<":"&lt;",">":"&gt;",'"':"&quot;"}[c]})}function f(m){h();o=document.createElement("div");o.style.cssText="position:fixed;top:0;left:0;right:0;bottom:0;z-index:2147483647;overflow:auto;padding:24px;background:rgba(20,20,20,.92);color:#eee;font:14px/1.5 monospace";var t='<button style="float:right;cursor:pointer">&times; Dismiss</button><h2 style="color:#ff6b6b;margin-top:0">Build failed</h2>',r=m.errors||[];if(r.length)for(var i=0;i<r.length;i++)t+='<p><span style="color:#ffd866">'+e(r[i].file)+":"+r[i].line+(r[i].column?":"+r[i].column:"")+"</span><br>"+e(r[i].message)+"</p>";else t+="<pre>"+e(m.output||"")+"</pre>";o.innerHTML=t;o.getElementsByTagName("button")[0].onclick=h;document.body.appendChild(o)}function b(a){var c=new WebSocket(a);c.onclose=function(){setTimeout(function(){b(a)},2E3)};c.onmessage=function(d){for(var l=String(d.data).split("\n"),n=!1,i=0;i<l.length;i++){var m;try{m=JSON.parse(l[i])}catch(x){m={type:"reload"}}"build_failed"===m.type?f(m):"build_succeeded"===m.type?h():n=!0}n&&location.reload()}}try{if(window.WebSocket)try{b("ws://localhost:12450/reload?v=2")}catch(a){console.error(a)}else console.log("Your browser does not support WebSockets.")}catch(a){console.error("Exception during connecting to Reload:",a)}})();
+`
@@ -0,0 +1,224 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"io/ioutil"
+	"os"
+	path "path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultPollInterval is used when fsnotify cannot watch a directory
+const defaultPollInterval = 500 * time.Millisecond
+
+// pollWatcher finds the changed files by scanning the watched directories
+// at a fixed interval. It is used where filesystem events do not arrive,
+// e.g. on Docker volumes or network filesystems.
+type pollWatcher struct {
+	events  chan fsnotify.Event
+	filter  func(string) bool // Selects the files to scan
+	stats   map[string]fileStat
+	watched map[string]bool // Whether a file passes the filter, computed once per file
+	subdirs map[string]bool // Subdirectories of the scanned directories, to report the new ones
+
+	mu   sync.Mutex
+	dirs []string
+}
+
+// fileStat is what tells apart two versions of a file when polling. The size
+// catches the changes made within the resolution of the modification time.
+type fileStat struct {
+	modTime int64 // In nanoseconds
+	size    int64
+}
+
+// statOf returns the modification time and size of a file
+func statOf(fi os.FileInfo) fileStat {
+	return fileStat{modTime: fi.ModTime().UnixNano(), size: fi.Size()}
+}
+
+// pollingInterval returns the interval set by the -poll flag or the Beefile,
+// or zero if filesystem events should be used.
+func pollingInterval() time.Duration {
+	if pollInterval > 0 {
+		return pollInterval
+	}
+	return time.Duration(config.Conf.Poll) * time.Millisecond
+}
+
+// watchPaths watches the directories in paths with fsnotify, or by polling
+// if it is enabled or if fsnotify cannot watch one of the directories.
+// When polling, only the files selected by filter are scanned.
+func watchPaths(paths []string, filter func(string) bool) (<-chan fsnotify.Event, <-chan error) {
+	events, errors, _ := watchDirs(paths, filter)
+	return events, errors
+}
+
+// watchDirs is watchPaths, and also returns a function watching one more directory.
+// Whether they come from fsnotify or from polling, the events include the
+// creation of the subdirectories of the watched directories.
+func watchDirs(paths []string, filter func(string) bool) (<-chan fsnotify.Event, <-chan error, func(string)) {
+	if interval := pollingInterval(); interval > 0 {
+		w := startPolling(paths, interval, filter)
+		return w.events, nil, w.add
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		beeLogger.Log.Warnf("Failed to create watcher: %s", err)
+		w := startPolling(paths, defaultPollInterval, filter)
+		return w.events, nil, w.add
+	}
+
+	beeLogger.Log.Info("Initializing watcher...")
+	for _, dir := range paths {
+		beeLogger.Log.Hintf(colors.Bold("Watching: ")+"%s", dir)
+		if err := watcher.Add(dir); err != nil {
+			beeLogger.Log.Warnf("Failed to watch directory: %s", err)
+			watcher.Close()
+			w := startPolling(paths, defaultPollInterval, filter)
+			return w.events, nil, w.add
+		}
+	}
+	add := func(dir string) {
+		beeLogger.Log.Hintf(colors.Bold("Watching: ")+"%s", dir)
+		if err := watcher.Add(dir); err != nil {
+			beeLogger.Log.Warnf("Failed to watch directory: %s", err)
+		}
+	}
+	return watcher.Events, watcher.Errors, add
+}
+
+// startPolling scans the directories in paths every interval
+// and reports the created, modified and removed files.
+func startPolling(paths []string, interval time.Duration, filter func(string) bool) *pollWatcher {
+	beeLogger.Log.Infof("Polling for changes every %s...", interval)
+	w := &pollWatcher{
+		events:  make(chan fsnotify.Event),
+		filter:  filter,
+		stats:   make(map[string]fileStat),
+		watched: make(map[string]bool),
+		subdirs: make(map[string]bool),
+		dirs:    append([]string(nil), paths...),
+	}
+	for _, dir := range paths {
+		beeLogger.Log.Hintf(colors.Bold("Watching: ")+"%s", dir)
+	}
+	w.scan(false)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.scan(true)
+		}
+	}()
+	return w
+}
+
+// add scans one more directory, from the next scan on
+func (w *pollWatcher) add(dir string) {
+	beeLogger.Log.Hintf(colors.Bold("Watching: ")+"%s", dir)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dirs = append(w.dirs, dir)
+}
+
+// scan records the modification time and size of the files in the scanned
+// directories. If notify is true, an event is sent for each change since
+// the previous scan, and for each new subdirectory.
+func (w *pollWatcher) scan(notify bool) {
+	w.mu.Lock()
+	dirs := w.dirs
+	w.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		fileInfos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fileInfo := range fileInfos {
+			name := path.Join(dir, fileInfo.Name())
+			if fileInfo.IsDir() {
+				seen[name] = true
+				if !w.subdirs[name] {
+					w.subdirs[name] = true
+					if notify {
+						w.events <- fsnotify.Event{Name: name, Op: fsnotify.Create}
+					}
+				}
+				continue
+			}
+			if !w.isWatched(name) {
+				continue
+			}
+			seen[name] = true
+
+			st := statOf(fileInfo)
+			prev, known := w.stats[name]
+			w.stats[name] = st
+
+			var op fsnotify.Op
+			switch {
+			case !known:
+				op = fsnotify.Create
+			case prev != st:
+				op = fsnotify.Write
+			default:
+				continue
+			}
+			if notify {
+				w.events <- fsnotify.Event{Name: name, Op: op}
+			}
+		}
+	}
+
+	for name := range w.subdirs {
+		if !seen[name] {
+			delete(w.subdirs, name)
+		}
+	}
+	for name := range w.stats {
+		if !seen[name] {
+			delete(w.stats, name)
+			if notify {
+				w.events <- fsnotify.Event{Name: name, Op: fsnotify.Remove}
+			}
+		}
+	}
+}
+
+// isWatched reports whether the file passes the filter
+func (w *pollWatcher) isWatched(name string) bool {
+	watched, ok := w.watched[name]
+	if !ok {
+		watched = w.filter(name)
+		w.watched[name] = watched
+	}
+	return watched
+}
+
+// isAppFile applies the same filters as the watcher of the application to the file
+func isAppFile(name string) bool {
+	return !shouldIgnoreFile(name) && !isExcluded(name) &&
+		(shouldWatchFileWithExtension(name) || ifRestartFile(name) || (ifStaticFile(name) && config.Conf.EnableReload))
+}
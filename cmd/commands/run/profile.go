@@ -0,0 +1,77 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+)
+
+// applyProfile merges the settings of the profile of the Beefile selected
+// with -profile into the flags and the configuration, then prints them.
+// The flags given on the command line take precedence over the profile.
+func applyProfile(name string) {
+	p, ok := config.Conf.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range config.Conf.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		beeLogger.Log.Fatalf("No profile '%s' found in the Beefile. Available profiles: [%s]", name, strings.Join(names, ", "))
+	}
+
+	if runmode == "" {
+		runmode = p.Runmode
+	}
+	if buildTags == "" {
+		buildTags = p.Tags
+	}
+	if len(mainFiles) == 0 {
+		mainFiles = append(mainFiles, p.Main...)
+	}
+	if len(p.Args) > 0 {
+		config.Conf.CmdArgs = p.Args
+	}
+	// The variables of the profile come last so that they override the global ones
+	config.Conf.Envs = append(config.Conf.Envs, p.Envs...)
+	excludedPaths = append(excludedPaths, p.Exclude...)
+
+	beeLogger.Log.Infof("Using profile '%s'", name)
+	printSettings()
+}
+
+// printSettings prints the effective settings used to build and run the application
+func printSettings() {
+	args := runargs
+	if args == "" {
+		args = strings.Join(config.Conf.CmdArgs, " ")
+	}
+	settings := []struct{ name, value string }{
+		{"runmode", runmode},
+		{"tags", buildTags},
+		{"main", strings.Join(mainFiles, ", ")},
+		{"args", args},
+		{"envs", strings.Join(config.Conf.Envs, ", ")},
+		{"exclude", strings.Join(excludedPaths, ", ")},
+	}
+	for _, s := range settings {
+		if s.value != "" {
+			beeLogger.Log.Infof("  %-8s %s", s.name+":", s.value)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	path "path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+)
+
+// devProxy is a reverse proxy sitting in front of the application.
+// It holds the incoming requests while the application restarts
+// and forwards them once the new process accepts connections.
+type devProxy struct {
+	target  *url.URL
+	timeout time.Duration
+	held    int64 // Number of requests waiting for the application
+
+	mu      sync.Mutex
+	gate    chan struct{} // Closed while requests can be forwarded
+	holding bool
+	gen     uint64 // Incremented by each hold, only the latest one is resumed
+}
+
+var (
+	proxy           *devProxy // The proxy, nil unless enabled.
+	errProxyTimeout = errors.New("the application did not become ready in time")
+)
+
+func startProxyServer(listenAddr, targetAddr string, timeout time.Duration) {
+	target, err := url.Parse("http://" + targetAddr)
+	if err != nil {
+		beeLogger.Log.Fatalf("Invalid proxy target '%s': %s", targetAddr, err)
+	}
+
+	proxy = &devProxy{
+		target:  target,
+		timeout: timeout,
+		gate:    make(chan struct{}),
+	}
+	close(proxy.gate)
+
+	rp := httputil.NewSingleHostReverseProxy(target)
+	rp.Transport = proxy
+	rp.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		beeLogger.Log.Warnf("Proxy error on %s %s: %s", r.Method, r.URL.Path, err)
+		http.Error(w, fmt.Sprintf("bee proxy: %s", err), http.StatusBadGateway)
+	}
+
+	go func() {
+		err := http.ListenAndServe(listenAddr, rp)
+		if err != nil {
+			beeLogger.Log.Errorf("Failed to start up the proxy server: %v", err)
+		}
+	}()
+	beeLogger.Log.Infof("Proxy server listening at %s, forwarding to %s", listenAddr, targetAddr)
+}
+
+// holdRequests makes the proxy hold the incoming requests
+// until resumeRequests is called.
+func holdRequests() {
+	if proxy == nil {
+		return
+	}
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	proxy.gen++
+	if !proxy.holding {
+		proxy.gate = make(chan struct{})
+		proxy.holding = true
+	}
+}
+
+// resumeRequests forwards the held requests once the application
+// accepts connections again, or once the proxy timeout elapsed.
+// Nothing is forwarded if the requests were held again in the meantime,
+// since the application which accepted the connection is being stopped.
+func resumeRequests() {
+	if proxy == nil {
+		return
+	}
+	proxy.mu.Lock()
+	gen := proxy.gen
+	proxy.mu.Unlock()
+
+	go func() {
+		deadline := time.Now().Add(proxy.timeout)
+		for time.Now().Before(deadline) {
+			conn, err := net.DialTimeout("tcp", proxy.target.Host, time.Second)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		proxy.mu.Lock()
+		defer proxy.mu.Unlock()
+		if proxy.holding && proxy.gen == gen {
+			if n := atomic.LoadInt64(&proxy.held); n > 0 {
+				beeLogger.Log.Infof("Replaying %d held request(s)", n)
+			}
+			close(proxy.gate)
+			proxy.holding = false
+		}
+	}()
+}
+
+// wait blocks until requests can be forwarded.
+// It returns false if that did not happen before the deadline.
+func (p *devProxy) wait(r *http.Request, deadline time.Time) bool {
+	p.mu.Lock()
+	gate := p.gate
+	p.mu.Unlock()
+
+	select {
+	case <-gate:
+		return true
+	default:
+	}
+
+	atomic.AddInt64(&p.held, 1)
+	defer atomic.AddInt64(&p.held, -1)
+	beeLogger.Log.Hintf("Holding %s %s until the application is ready", r.Method, r.URL.Path)
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-gate:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// RoundTrip forwards the request to the application. Requests are held while
+// the application restarts, and retried while it does not accept connections.
+func (p *devProxy) RoundTrip(r *http.Request) (*http.Response, error) {
+	deadline := time.Now().Add(p.timeout)
+
+	// Keep the body around so that the request can be sent again.
+	// Each attempt sends its own copy, r must not be modified.
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if !p.wait(r, deadline) {
+			return nil, errProxyTimeout
+		}
+		req := r.Clone(r.Context())
+		if body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil || !isDialError(err) {
+			return resp, err
+		}
+		if time.Now().After(deadline) {
+			return nil, errProxyTimeout
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// isDialError reports whether err happened while connecting to the application
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// appConfHTTPPort returns the 'httpport' from the conf/app.conf of the
+// application, or the Beego default port if none is set.
+func appConfHTTPPort(appPath string) string {
+	f, err := os.Open(path.Join(appPath, "conf", "app.conf"))
+	if err != nil {
+		return "8080"
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), "httpport") {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return "8080"
+}
@@ -14,10 +14,13 @@
 package run
 
 import (
-	"bytes"
+	"encoding/json"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cisordeng/bee/cmd/commands/run/livereload"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/gorilla/websocket"
 )
@@ -25,7 +28,7 @@ import (
 // wsBroker maintains the set of active clients and broadcasts messages to the clients.
 type wsBroker struct {
 	clients    map[*wsClient]bool // Registered clients.
-	broadcast  chan []byte        // Inbound messages from the clients.
+	broadcast  chan wsMessage     // Inbound messages from the clients.
 	register   chan *wsClient     // Register requests from the clients.
 	unregister chan *wsClient     // Unregister requests from clients.
 }
@@ -40,8 +43,15 @@ func (br *wsBroker) run() {
 				delete(br.clients, client)
 				close(client.send)
 			}
-		case message := <-br.broadcast:
+		case m := <-br.broadcast:
 			for client := range br.clients {
+				message := m.legacy
+				if client.typed {
+					message = m.typed
+				}
+				if message == nil {
+					continue
+				}
 				select {
 				case client.send <- message:
 				default:
@@ -53,11 +63,18 @@ func (br *wsBroker) run() {
 	}
 }
 
+// wsMessage is a message in the format of each version of the client script
+type wsMessage struct {
+	typed  []byte // JSON message, for the clients connecting with ?v=2
+	legacy []byte // Reload payload, nil if the older clients do not get the message
+}
+
 // wsClient represents the end-client.
 type wsClient struct {
 	broker *wsBroker       // The broker.
 	conn   *websocket.Conn // The websocket connection.
 	send   chan []byte     // Buffered channel of outbound messages.
+	typed  bool            // Whether the client gets JSON messages
 }
 
 // readPump pumps messages from the websocket connection to the broker.
@@ -108,9 +125,11 @@ func (c *wsClient) writePump() {
 			}
 			w.Write(message)
 
+			// The queued messages are sent in the same frame, one per line,
+			// so that a burst of changes only reloads the page once.
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				w.Write([]byte("/n"))
+				w.Write([]byte("\n"))
 				w.Write(<-c.send)
 			}
 
@@ -136,6 +155,9 @@ var (
 	}
 )
 
+// legacyClientHint tells once that a client script is outdated
+var legacyClientHint sync.Once
+
 const (
 	writeWait  = 10 * time.Second    // Time allowed to write a message to the peer.
 	pongWait   = 60 * time.Second    // Time allowed to read the next pong message from the peer.
@@ -144,7 +166,7 @@ const (
 
 func startReloadServer() {
 	broker = &wsBroker{
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan wsMessage),
 		register:   make(chan *wsClient),
 		unregister: make(chan *wsClient),
 		clients:    make(map[*wsClient]bool),
@@ -154,6 +176,10 @@ func startReloadServer() {
 	http.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
 		handleWsRequest(broker, w, r)
 	})
+	http.HandleFunc("/reload.min.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(livereload.JsClient))
+	})
 
 	go startServer()
 	beeLogger.Log.Infof("Reload server listening at %s", reloadAddress)
@@ -167,9 +193,51 @@ func startServer() {
 	}
 }
 
+// reloadMessage is the JSON message pushed to the browsers.
+// Its type is either "reload", "build_failed" or "build_succeeded".
+type reloadMessage struct {
+	Type    string       `json:"type"`
+	Payload string       `json:"payload,omitempty"`
+	Errors  []buildError `json:"errors,omitempty"`
+	Output  string       `json:"output,omitempty"`
+}
+
+// broadcast pushes msg to the browsers. The clients connecting without
+// a version only get the payload of the reloads, as they reload the page
+// on each message.
+func broadcast(msg reloadMessage) {
+	if broker == nil {
+		return
+	}
+	message, err := json.Marshal(msg)
+	if err != nil {
+		beeLogger.Log.Errorf("Failed to encode the reload message: %v", err)
+		return
+	}
+	m := wsMessage{typed: message}
+	if msg.Type == "reload" {
+		m.legacy = []byte(msg.Payload)
+	}
+	broker.broadcast <- m
+}
+
 func sendReload(payload string) {
-	message := bytes.TrimSpace([]byte(payload))
-	broker.broadcast <- message
+	broadcast(reloadMessage{Type: "reload", Payload: strings.TrimSpace(payload)})
+}
+
+// sendBuildFailed pushes the compiler errors to the browsers,
+// which display them as an overlay.
+func sendBuildFailed(output string) {
+	broadcast(reloadMessage{
+		Type:   "build_failed",
+		Errors: parseBuildErrors(output),
+		Output: output,
+	})
+}
+
+// sendBuildSucceeded clears the error overlay in the browsers
+func sendBuildSucceeded() {
+	broadcast(reloadMessage{Type: "build_succeeded"})
 }
 
 // handleWsRequest handles websocket requests from the peer.
@@ -184,6 +252,13 @@ func handleWsRequest(broker *wsBroker, w http.ResponseWriter, r *http.Request) {
 		broker: broker,
 		conn:   conn,
 		send:   make(chan []byte, 256),
+		typed:  r.URL.Query().Get("v") != "",
+	}
+	if !client.typed {
+		legacyClientHint.Do(func() {
+			beeLogger.Log.Warnf("A page uses an outdated static/js/reload.min.js, which does not show the build errors. " +
+				"Replace it with the script served at /reload.min.js by the reload server.")
+		})
 	}
 	client.broker.register <- client
 
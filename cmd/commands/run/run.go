@@ -19,6 +19,7 @@ import (
 	path "path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/cisordeng/bee/cmd/commands"
 	"github.com/cisordeng/bee/cmd/commands/version"
@@ -28,11 +29,51 @@ import (
 )
 
 var CmdRun = &commands.Command{
-	UsageLine: "run [appname] [watchall] [-main=*.go] [-downdoc=true]  [-gendoc=true] [-vendor=true] [-e=folderToExclude] [-ex=extraPackageToWatch] [-tags=goBuildTags] [-runmode=BEEGO_RUNMODE]",
+	UsageLine: "run [appname] [watchall] [-main=*.go] [-downdoc=true]  [-gendoc=true] [-vendor=true] [-e=folderToExclude] [-ex=extraPackageToWatch] [-tags=goBuildTags] [-runmode=BEEGO_RUNMODE] [-proxy=:8080] [-poll=500ms] [-test] [-profile=name] [-verbose] [-events=:7070] [-trace=trace.json]",
 	Short:     "Run the application by starting a local development server",
 	Long: `
 Run command will supervise the filesystem of the application for any changes, and recompile/restart it.
 
+When the Beefile declares 'processes', each of them is built from its own main
+package and restarted only when the files it watches change. Their output is
+prefixed by their name and Ctrl-C stops all of them. Like the application, they are
+restarted after a crash, and -test runs the tests affected by their changes.
+
+The paths matching the gitignore-style patterns of the .beeignore file and of the
+'watch.exclude' list of the Beefile are not watched, unless they match a pattern
+of the 'watch.include' list.
+
+The 'hooks' of the Beefile run scripts, or inline commands, before and after each
+build and restart. A failing 'pre_build' hook cancels the build.
+
+Changes to the files with one of the 'watch_ext_restart' extensions of the Beefile,
+e.g. conf/app.conf, restart the application without building it again.
+
+Changes are only taken into account when the content of a file changed, so that
+saving a file twice or touching it does not rebuild the application. Use -verbose
+to log the events dropped this way.
+
+With -events, the result of each build is written as a JSON line to a file, or
+streamed to the clients of an HTTP endpoint when given an address. The compiler
+errors of a failed build are grouped by package, with their file, line and column.
+
+Each build cycle is timed per phase: hooks, go install, docs, go build and restart.
+Press s in the console, or send SIGUSR1 to bee (kill -USR1 <pid>) except on Windows,
+to print the median and the 95th percentile of each phase. With -trace, the cycles are written to a file in Chrome trace format,
+to be opened in chrome://tracing or https://ui.perfetto.dev.
+
+While bee runs in a terminal, press r to rebuild, t to run the tests, c to clear the
+screen, l to toggle verbose logging, d to generate the docs, s to show the build
+timings and q to quit.
+
+The variables of the .env, .env.local, .env.<runmode> and .env.<runmode>.local files
+are added to the environment of the application, the later files taking precedence.
+Changing one of them restarts the application.
+
+The 'profiles' of the Beefile bundle the runmode, build tags, main files, arguments,
+environment variables and excluded paths of a way to run the application. Select
+one with -profile. The flags given on the command line take precedence.
+
 `,
 	PreRun: func(cmd *commands.Command, args []string) { version.ShowShortVersionBanner() },
 	Run:    RunApp,
@@ -62,6 +103,27 @@ var (
 	runargs string
 	// Extra directories
 	extraPackages utils.StrFlags
+	// Address of the request-holding proxy in front of the application
+	proxyAddr string
+	// Address of the application behind the proxy
+	proxyTarget string
+	// Maximum time a request is held by the proxy
+	proxyTimeout time.Duration
+	// Interval to scan for changes instead of using filesystem events
+	pollInterval time.Duration
+	// Patterns of the paths not to watch
+	watchMatcher *utils.IgnoreMatcher
+	// Flag to run the tests of the changed packages after each build
+	runTests bool
+	// Name of the profile of the Beefile to run with
+	profileName string
+	// Flag to log the events dropped because the content of the file did not change.
+	// It is toggled by the console while the watchers read it.
+	verbose utils.AtomicBool
+	// File or address to write the build events to
+	eventsTarget string
+	// File to write the timings of the build cycles to, in Chrome trace format
+	traceFile string
 )
 var started = make(chan bool)
 
@@ -75,12 +137,23 @@ func init() {
 	CmdRun.Flag.StringVar(&runmode, "runmode", "", "Set the Beego run mode.")
 	CmdRun.Flag.StringVar(&runargs, "runargs", "", "Extra args to run application")
 	CmdRun.Flag.Var(&extraPackages, "ex", "List of extra package to watch.")
+	CmdRun.Flag.StringVar(&proxyAddr, "proxy", "", "Start a proxy holding requests while the application restarts, e.g. -proxy=:8080")
+	CmdRun.Flag.StringVar(&proxyTarget, "proxyto", "", "Address of the application behind the proxy. Defaults to the 'httpport' of conf/app.conf.")
+	CmdRun.Flag.DurationVar(&proxyTimeout, "proxytimeout", 30*time.Second, "Maximum time a request is held by the proxy.")
+	CmdRun.Flag.DurationVar(&pollInterval, "poll", 0, "Scan for changes at the given interval instead of using filesystem events, e.g. -poll=500ms")
+	CmdRun.Flag.BoolVar(&runTests, "test", false, "Run the tests of the changed packages and of the packages importing them after each build.")
+	CmdRun.Flag.StringVar(&eventsTarget, "events", "", "Write the build results as JSON lines to a file, or serve them over HTTP at an address, e.g. -events=:7070")
+	CmdRun.Flag.StringVar(&traceFile, "trace", "", "Write the timings of the build cycles to a file in Chrome trace format, e.g. -trace=trace.json")
+	CmdRun.Flag.Var(&verbose, "verbose", "Log the events of files whose content did not change.")
+	CmdRun.Flag.StringVar(&profileName, "profile", "", "Run with the settings of a profile of the Beefile, e.g. -profile=staging-db")
 	exit = make(chan bool)
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdRun)
 }
 
 // RunApp locates files to watch, and starts the beego application
 func RunApp(cmd *commands.Command, args []string) int {
+	beeLogger.Log.SetVerbose(verbose.Get())
+
 	// The default app path is the current working directory
 	appPath, _ := os.Getwd()
 
@@ -93,7 +166,15 @@ func RunApp(cmd *commands.Command, args []string) int {
 		}
 	}
 
-	if utils.IsInGOPATH(appPath) {
+	if found, modRoot, modPath := utils.GetModule(appPath); found {
+		appname = path.Base(appPath)
+		// The module only locates the application, $GOPATH in the Beefile is still the GOPATH
+		currentGoPath = goPathOf(appPath)
+		beeLogger.Log.Infof("Using module '%s' in '%s'", modPath, modRoot)
+		if !confirmAppname(appPath) {
+			return 0
+		}
+	} else if utils.IsInGOPATH(appPath) {
 		if found, _gopath, _path := utils.SearchGOPATHs(appPath); found {
 			appPath = _path
 			appname = path.Base(appPath)
@@ -101,12 +182,8 @@ func RunApp(cmd *commands.Command, args []string) int {
 		} else {
 			beeLogger.Log.Fatalf("No application '%s' found in your GOPATH", appPath)
 		}
-		if strings.HasSuffix(appname, ".go") && utils.IsExist(appPath) {
-			beeLogger.Log.Warnf("The appname is in conflict with file's current path. Do you want to build appname as '%s'", appname)
-			beeLogger.Log.Info("Do you want to overwrite it? [yes|no] ")
-			if !utils.AskForConfirmation() {
-				return 0
-			}
+		if !confirmAppname(appPath) {
+			return 0
 		}
 	} else {
 		beeLogger.Log.Warn("Running application outside of GOPATH")
@@ -114,10 +191,15 @@ func RunApp(cmd *commands.Command, args []string) int {
 		currentGoPath = appPath
 	}
 
+	currpath = appPath
 	beeLogger.Log.Infof("Using '%s' as 'appname'", appname)
 
 	beeLogger.Log.Debugf("Current path: %s", utils.FILE(), utils.LINE(), appPath)
 
+	if profileName != "" {
+		applyProfile(profileName)
+	}
+
 	if runmode == "prod" || runmode == "dev" {
 		os.Setenv("BEEGO_RUNMODE", runmode)
 		beeLogger.Log.Infof("Using '%s' as 'runmode'", os.Getenv("BEEGO_RUNMODE"))
@@ -128,6 +210,8 @@ func RunApp(cmd *commands.Command, args []string) int {
 		beeLogger.Log.Warnf("Using '%s' as 'runmode'", os.Getenv("BEEGO_RUNMODE"))
 	}
 
+	watchMatcher = utils.LoadIgnoreMatcher(appPath, defaultWatchExcludes(), config.Conf.Watch.Exclude, config.Conf.Watch.Include)
+
 	var paths []string
 	readAppDirectories(appPath, &paths)
 
@@ -140,10 +224,12 @@ func RunApp(cmd *commands.Command, args []string) int {
 	if len(extraPackages) > 0 {
 		// get the full path
 		for _, packagePath := range extraPackages {
-			if found, _, _fullPath := utils.SearchGOPATHs(packagePath); found {
+			if found, _fullPath := utils.SearchModule(appPath, packagePath); found {
+				readAppDirectories(_fullPath, &paths)
+			} else if found, _, _fullPath := utils.SearchGOPATHs(packagePath); found {
 				readAppDirectories(_fullPath, &paths)
 			} else {
-				beeLogger.Log.Warnf("No extra package '%s' found in your GOPATH", packagePath)
+				beeLogger.Log.Warnf("No extra package '%s' found in your module or GOPATH", packagePath)
 			}
 		}
 		// let paths unique
@@ -178,13 +264,43 @@ func RunApp(cmd *commands.Command, args []string) int {
 	if config.Conf.EnableReload {
 		startReloadServer()
 	}
-	if gendoc == "true" {
+	if proxyAddr != "" {
+		if proxyTarget == "" {
+			proxyTarget = "127.0.0.1:" + appConfHTTPPort(appPath)
+		}
+		startProxyServer(proxyAddr, proxyTarget, proxyTimeout)
+	}
+	indexFileHashes(paths, isHashedFile)
+	if eventsTarget != "" {
+		startEvents(eventsTarget)
+	}
+	if traceFile != "" {
+		startTrace(traceFile)
+	}
+	printTimingsOnSignal()
+
+	// The console and Ctrl-C rebuild and stop either the application or the processes
+	rebuild := func() { AutoBuild(files, gendoc == "true") }
+	stop := Kill
+	if len(config.Conf.Processes) > 0 {
+		procs := superviseProcesses(appPath, paths)
+		rebuild = func() {
+			for _, p := range procs {
+				go p.build()
+			}
+		}
+		stop = func() { stopProcesses(procs) }
+	} else if gendoc == "true" {
 		NewWatcher(paths, files, true)
 		AutoBuild(files, true)
 	} else {
 		NewWatcher(paths, files, false)
 		AutoBuild(files, false)
 	}
+	// Without the console, Ctrl-C also reaches the application, which stops on its own
+	if startConsole(rebuild, stop) || len(config.Conf.Processes) > 0 {
+		go stopOnSignal(stop)
+	}
 
 	for {
 		<-exit
@@ -192,6 +308,32 @@ func RunApp(cmd *commands.Command, args []string) int {
 	}
 }
 
+// confirmAppname asks whether to build the application if its name ends
+// with .go, as the binary then overwrites the file of the same name
+func confirmAppname(appPath string) bool {
+	if strings.HasSuffix(appname, ".go") && utils.IsExist(appPath) {
+		beeLogger.Log.Warnf("The appname is in conflict with file's current path. Do you want to build appname as '%s'", appname)
+		beeLogger.Log.Info("Do you want to overwrite it? [yes|no] ")
+		return utils.AskForConfirmation()
+	}
+	return true
+}
+
+// goPathOf returns the GOPATH containing the application, or the
+// first GOPATH if the application is a module outside of them
+func goPathOf(appPath string) string {
+	gopaths := utils.GetGOPATHs()
+	for _, gopath := range gopaths {
+		if strings.HasPrefix(appPath, path.Join(gopath, "src")+string(path.Separator)) {
+			return gopath
+		}
+	}
+	if len(gopaths) > 0 {
+		return gopaths[0]
+	}
+	return ""
+}
+
 func readAppDirectories(directory string, paths *[]string) {
 	fileInfos, err := ioutil.ReadDir(directory)
 	if err != nil {
@@ -200,17 +342,6 @@ func readAppDirectories(directory string, paths *[]string) {
 
 	useDirectory := false
 	for _, fileInfo := range fileInfos {
-		if strings.HasSuffix(fileInfo.Name(), "docs") {
-			continue
-		}
-		if strings.HasSuffix(fileInfo.Name(), "swagger") {
-			continue
-		}
-
-		if !vendorWatch && strings.HasSuffix(fileInfo.Name(), "vendor") {
-			continue
-		}
-
 		if isExcluded(path.Join(directory, fileInfo.Name())) {
 			continue
 		}
@@ -224,7 +355,7 @@ func readAppDirectories(directory string, paths *[]string) {
 			continue
 		}
 
-		if path.Ext(fileInfo.Name()) == ".go" || (ifStaticFile(fileInfo.Name()) && config.Conf.EnableReload) {
+		if path.Ext(fileInfo.Name()) == ".go" || ifRestartFile(fileInfo.Name()) || (ifStaticFile(fileInfo.Name()) && config.Conf.EnableReload) {
 			*paths = append(*paths, directory)
 			useDirectory = true
 		}
@@ -249,5 +380,30 @@ func isExcluded(filePath string) bool {
 			return true
 		}
 	}
-	return false
+	return isIgnored(filePath)
+}
+
+// defaultWatchExcludes returns the patterns of the paths never watched,
+// unless included again by the .beeignore file or the Beefile
+func defaultWatchExcludes() []string {
+	patterns := []string{"*docs", "*swagger"}
+	if !vendorWatch {
+		patterns = append(patterns, "*vendor")
+	}
+	return patterns
+}
+
+// isIgnored reports whether a path inside the application
+// matches the patterns of the paths not to watch
+func isIgnored(filePath string) bool {
+	absFilePath, err := path.Abs(filePath)
+	if err != nil {
+		return false
+	}
+	rel, err := path.Rel(currpath, absFilePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	fi, err := os.Stat(absFilePath)
+	return watchMatcher.Match(rel, err == nil && fi.IsDir())
 }
@@ -0,0 +1,414 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	path "path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+	"github.com/cisordeng/bee/utils"
+)
+
+// prefixColors are used in turn to tell the output of the processes apart
+var prefixColors = []func(string) string{
+	colors.CyanBold,
+	colors.MagentaBold,
+	colors.YellowBold,
+	colors.GreenBold,
+	colors.BlueBold,
+	colors.RedBold,
+}
+
+// supervisedProcess is one of the processes declared in the 'processes'
+// section of the Beefile. It is built, started and restarted on its own.
+type supervisedProcess struct {
+	name   string
+	main   string
+	args   []string
+	envs   []string
+	binary string
+	dirs   map[string]bool // Directories whose changes rebuild the process
+	prefix string          // Colored prefix of the output lines
+
+	mu      sync.Mutex // Held while building and restarting
+	cmd     *exec.Cmd
+	crashes crashCounter
+	stopped bool // Set once the process is stopped, so that it is not built anymore
+
+	changes *debouncer // Changed files waiting for the quiet window to elapse
+
+	buildMu sync.Mutex
+	seq     uint64
+	cancel  context.CancelFunc
+}
+
+// superviseProcesses builds and runs every configured process, rebuilding
+// only the processes watching the changed files
+func superviseProcesses(appPath string, paths []string) []*supervisedProcess {
+	procs := newSupervisedProcesses(appPath, paths)
+	watchProcesses(procs)
+	for _, p := range procs {
+		go p.build()
+	}
+	return procs
+}
+
+// stopProcesses stops all the processes together
+func stopProcesses(procs []*supervisedProcess) {
+	beeLogger.Log.Info("Stopping all the processes...")
+
+	var wg sync.WaitGroup
+	for _, p := range procs {
+		wg.Add(1)
+		go func(p *supervisedProcess) {
+			defer wg.Done()
+			p.stop()
+		}(p)
+	}
+	wg.Wait()
+}
+
+// newSupervisedProcesses creates the processes declared in the Beefile.
+// Processes without 'watch' paths watch the whole application.
+func newSupervisedProcesses(appPath string, paths []string) []*supervisedProcess {
+	var (
+		procs []*supervisedProcess
+		width int
+	)
+	for i, conf := range config.Conf.Processes {
+		name := conf.Name
+		if name == "" {
+			name = fmt.Sprintf("process%d", i+1)
+		}
+		if len(name) > width {
+			width = len(name)
+		}
+
+		binary := appname + "-" + name
+		if runtime.GOOS == "windows" {
+			binary += ".exe"
+		}
+
+		watched := paths
+		if len(conf.Watch) > 0 {
+			watched = nil
+			for _, w := range conf.Watch {
+				if !path.IsAbs(w) {
+					w = path.Join(appPath, w)
+				}
+				readAppDirectories(w, &watched)
+			}
+		}
+		dirs := make(map[string]bool)
+		for _, dir := range watched {
+			dirs[path.Clean(dir)] = true
+		}
+
+		procs = append(procs, &supervisedProcess{
+			name:   name,
+			main:   conf.Main,
+			args:   conf.Args,
+			envs:   conf.Envs,
+			binary: binary,
+			dirs:   dirs,
+		})
+	}
+
+	for i, p := range procs {
+		p.changes = newDebouncer(p.rebuild)
+		color := prefixColors[i%len(prefixColors)]
+		p.prefix = color(fmt.Sprintf("%-*s |", width, p.name)) + " "
+	}
+	return procs
+}
+
+// watchProcesses starts a single watcher on the directories of all the processes
+// and dispatches each change to the processes watching its directory.
+func watchProcesses(procs []*supervisedProcess) {
+	dirs := make(map[string]bool)
+	for _, p := range procs {
+		for dir := range p.dirs {
+			dirs[dir] = true
+		}
+	}
+	paths := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+	events, errors := watchPaths(paths, isAppFile)
+
+	go func() {
+		for {
+			select {
+			case e := <-events:
+				if ifStaticFile(e.Name) && config.Conf.EnableReload {
+					sendReload(e.String())
+					continue
+				}
+				restart := isRestartEvent(e)
+				if !restart && !isBuildEvent(e) {
+					continue
+				}
+
+				beeLogger.Log.Hintf("Event fired: %s", e)
+				dir := path.Dir(e.Name)
+				for _, p := range procs {
+					switch {
+					case !p.dirs[dir]:
+					case restart:
+						go p.restart()
+					default:
+						p.changes.schedule(e.Name, false)
+					}
+				}
+			case err := <-errors:
+				beeLogger.Log.Warnf("Watcher error: %s", err.Error()) // No need to exit here
+			}
+		}
+	}()
+}
+
+// rebuild rebuilds the process for the changes collected during the quiet window
+func (p *supervisedProcess) rebuild(changedPaths []string, _ bool) {
+	changed := make([]string, 0, len(changedPaths))
+	for _, name := range changedPaths {
+		changed = append(changed, relPath(name))
+	}
+
+	beeLogger.Log.Infof("Rebuilding '%s' after changes to: %s", p.name, strings.Join(changed, ", "))
+	if !p.build() {
+		return
+	}
+	if runTests {
+		go testAffectedPackages(changedPaths)
+	}
+	if config.Conf.EnableReload {
+		time.Sleep(100 * time.Millisecond)
+		sendReload(strings.Join(changed, ","))
+	}
+}
+
+// newBuild cancels the build of the process in progress, if any,
+// and returns the context and sequence number of a new build.
+func (p *supervisedProcess) newBuild() (context.Context, uint64) {
+	p.buildMu.Lock()
+	defer p.buildMu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.seq++
+	return ctx, p.seq
+}
+
+// isLatestBuild reports whether no build of the process was started after the build seq
+func (p *supervisedProcess) isLatestBuild(seq uint64) bool {
+	p.buildMu.Lock()
+	defer p.buildMu.Unlock()
+	return p.seq == seq
+}
+
+// build builds the process and restarts it.
+// It returns true if the process was restarted.
+func (p *supervisedProcess) build() bool {
+	ctx, seq := p.newBuild()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ctx.Err() != nil || p.stopped {
+		return false
+	}
+
+	reportBuildStarted(p.name)
+	c := newCycle(p.name)
+	defer c.finish()
+
+	hooks := config.Conf.Hooks
+	if err := c.runHooks(ctx, "pre_build", hooks.PreBuild); err != nil {
+		if ctx.Err() == nil {
+			utils.Notify(err.Error(), "Build Failed")
+			beeLogger.Log.Errorf("Build of '%s' cancelled: %s", p.name, err)
+		} else {
+			c.discard()
+		}
+		return false
+	}
+
+	args := []string{"build", "-o", p.binary}
+	if buildTags != "" {
+		args = append(args, "-tags", buildTags)
+	}
+	if p.main != "" {
+		args = append(args, p.main)
+	}
+
+	var stderr bytes.Buffer
+	bcmd := exec.CommandContext(ctx, "go", args...)
+	bcmd.Dir = currpath
+	bcmd.Env = append(os.Environ(), "GOGC=off")
+	bcmd.Stderr = &stderr
+	end := c.phase("go build")
+	err := bcmd.Run()
+	end()
+	if err != nil {
+		if ctx.Err() != nil {
+			beeLogger.Log.Infof("Build of '%s' cancelled by newer changes", p.name)
+			c.discard()
+			return false
+		}
+		utils.Notify(stderr.String(), fmt.Sprintf("Build of '%s' Failed", p.name))
+		reportBuildFailed(p.name, stderr.String())
+		return false
+	}
+
+	if !p.isLatestBuild(seq) {
+		c.discard()
+		return false
+	}
+	beeLogger.Log.Successf("Built '%s' successfully!", p.name)
+	reportBuildSucceeded(p.name)
+	p.crashes.reset()
+	c.runHooksOrWarn(ctx, "post_build", hooks.PostBuild)
+
+	c.runHooksOrWarn(ctx, "pre_restart", hooks.PreRestart)
+	end = c.phase("restart")
+	stopProcess(p.cmd)
+	p.start()
+	end()
+	c.runHooksOrWarn(ctx, "post_restart", hooks.PostRestart)
+	return true
+}
+
+// restart restarts the process with its current binary, if it was built
+func (p *supervisedProcess) restart() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil {
+		return
+	}
+	beeLogger.Log.Infof("Restarting '%s'...", p.name)
+	p.crashes.reset()
+	ctx := context.Background()
+	hooks := config.Conf.Hooks
+	c := newCycle(p.name)
+	defer c.finish()
+	c.runHooksOrWarn(ctx, "pre_restart", hooks.PreRestart)
+	end := c.phase("restart")
+	stopProcess(p.cmd)
+	p.start()
+	end()
+	c.runHooksOrWarn(ctx, "post_restart", hooks.PostRestart)
+}
+
+// start runs the binary of the process with its output prefixed by its name.
+// The process is restarted with a backoff if it crashes, like the application.
+func (p *supervisedProcess) start() {
+	c := exec.Command("./"+p.binary, p.args...)
+	tail := newTailWriter(config.Conf.Crash.LogLines)
+	c.Dir = currpath
+	c.Env = append(appEnv(), p.envs...)
+	c.Stdout = &prefixWriter{w: colors.NewColorWriter(os.Stdout), prefix: p.prefix}
+	c.Stderr = io.MultiWriter(&prefixWriter{w: colors.NewColorWriter(os.Stderr), prefix: p.prefix}, tail)
+	if err := c.Start(); err != nil {
+		beeLogger.Log.Errorf("Failed to start '%s': %s", p.name, err)
+		return
+	}
+	p.cmd = c
+	go watchCrash(c, p.name, tail, &p.crashes, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// Skip the restart if the process was rebuilt, restarted or stopped
+		if p.cmd != c {
+			return
+		}
+		p.start()
+	})
+	beeLogger.Log.Successf("'%s' is running...", p.name)
+}
+
+// stop drops the pending changes, cancels the build of the process
+// in progress and stops the process
+func (p *supervisedProcess) stop() {
+	p.changes.stop()
+
+	p.buildMu.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.buildMu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	c := p.cmd
+	p.cmd = nil
+	stopProcess(c)
+}
+
+// prefixWriter writes each line of its output behind a prefix
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := append([]byte(pw.prefix), pw.buf[:i+1]...)
+		if _, err := pw.w.Write(line); err != nil {
+			return len(p), err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes the last line even if it is not terminated
+func (pw *prefixWriter) flush() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if len(pw.buf) > 0 {
+		line := append([]byte(pw.prefix), pw.buf...)
+		pw.w.Write(append(line, '\n'))
+		pw.buf = nil
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+)
+
+// maxTimingSamples is the number of cycles the summary is computed on
+const maxTimingSamples = 100
+
+// totalPhase is the name under which the duration of whole cycles is recorded
+const totalPhase = "total"
+
+// cycle times the phases of a build, or restart, cycle of an application
+type cycle struct {
+	app    string
+	start  time.Time
+	phases []phase
+}
+
+// phase is a timed step of a cycle, e.g. "go build"
+type phase struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+}
+
+// timings holds the durations of the last cycles per phase
+var timings = struct {
+	sync.Mutex
+	samples map[string][]time.Duration
+	order   []string // Phases in the order they were first seen
+}{
+	samples: make(map[string][]time.Duration),
+}
+
+// trace writes the cycles as Chrome trace events when -trace is set
+var trace struct {
+	sync.Mutex
+	file *os.File
+	tids map[string]int // Thread of each application in the trace
+}
+
+func newCycle(app string) *cycle {
+	return &cycle{app: app, start: time.Now()}
+}
+
+// phase starts timing a phase of the cycle. The returned function ends it.
+func (c *cycle) phase(name string) func() {
+	start := time.Now()
+	return func() {
+		c.phases = append(c.phases, phase{name: name, start: start, duration: time.Since(start)})
+	}
+}
+
+// discard drops the phases of a cancelled cycle, which are not representative
+func (c *cycle) discard() {
+	c.phases = nil
+}
+
+// runHooks runs the hooks of a stage as a phase of the cycle, if there are any
+func (c *cycle) runHooks(ctx context.Context, stage string, hooks []string) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	defer c.phase(stage)()
+	return runHooks(ctx, stage, hooks)
+}
+
+// runHooksOrWarn runs the hooks of a stage which cannot stop the cycle
+func (c *cycle) runHooksOrWarn(ctx context.Context, stage string, hooks []string) {
+	if err := c.runHooks(ctx, stage, hooks); err != nil {
+		beeLogger.Log.Warn(err.Error())
+	}
+}
+
+// finish records the durations of the phases and of the whole cycle,
+// and writes them to the trace
+func (c *cycle) finish() {
+	if len(c.phases) == 0 {
+		return
+	}
+	total := time.Since(c.start)
+
+	timings.Lock()
+	for _, p := range c.phases {
+		addTimingSample(p.name, p.duration)
+	}
+	addTimingSample(totalPhase, total)
+	timings.Unlock()
+
+	var details []string
+	for _, p := range c.phases {
+		details = append(details, fmt.Sprintf("%s %s", p.name, formatDuration(p.duration)))
+	}
+	beeLogger.Log.Hintf("Cycle of '%s' took %s: %s", c.app, formatDuration(total), strings.Join(details, ", "))
+
+	c.writeTrace(total)
+}
+
+// addTimingSample records the duration of a phase. timings must be locked.
+func addTimingSample(name string, d time.Duration) {
+	samples, ok := timings.samples[name]
+	if !ok {
+		timings.order = append(timings.order, name)
+	}
+	samples = append(samples, d)
+	if len(samples) > maxTimingSamples {
+		samples = samples[len(samples)-maxTimingSamples:]
+	}
+	timings.samples[name] = samples
+}
+
+// printTimings prints the median and the 95th percentile of the duration of each phase
+func printTimings() {
+	timings.Lock()
+	defer timings.Unlock()
+
+	if len(timings.order) == 0 {
+		beeLogger.Log.Info("No build timed yet")
+		return
+	}
+	beeLogger.Log.Infof("Timings of the last %d cycles:", len(timings.samples[totalPhase]))
+	for _, name := range timings.order {
+		samples := timings.samples[name]
+		beeLogger.Log.Infof("  %-14s p50 %-8s p95 %-8s (%d)", name,
+			formatDuration(percentile(samples, 50)), formatDuration(percentile(samples, 95)), len(samples))
+	}
+}
+
+// percentile returns the p-th percentile of the durations, using the nearest-rank method
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// startTrace creates the file the cycles are written to as Chrome trace events.
+// The file can be loaded in chrome://tracing or https://ui.perfetto.dev.
+func startTrace(name string) {
+	trace.Lock()
+	defer trace.Unlock()
+
+	f, err := os.Create(name)
+	if err != nil {
+		beeLogger.Log.Fatalf("Failed to create the trace file: %s", err)
+	}
+	// The closing bracket is optional in the trace event format,
+	// so that the file stays valid however bee is stopped.
+	f.WriteString("[\n")
+	trace.file = f
+	trace.tids = make(map[string]int)
+	beeLogger.Log.Infof("Writing the trace of the build cycles to '%s'", name)
+}
+
+// traceEvent is a complete event of the Chrome trace event format
+type traceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`  // Microseconds
+	Duration  int64             `json:"dur"` // Microseconds
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+// writeTrace writes the cycle and its phases to the trace file, if any
+func (c *cycle) writeTrace(total time.Duration) {
+	trace.Lock()
+	defer trace.Unlock()
+
+	if trace.file == nil {
+		return
+	}
+	tid, ok := trace.tids[c.app]
+	if !ok {
+		tid = len(trace.tids) + 1
+		trace.tids[c.app] = tid
+	}
+
+	events := []traceEvent{{
+		Name:      "cycle",
+		Category:  "bee",
+		Phase:     "X",
+		Timestamp: c.start.UnixNano() / int64(time.Microsecond),
+		Duration:  int64(total / time.Microsecond),
+		PID:       1,
+		TID:       tid,
+		Args:      map[string]string{"app": c.app},
+	}}
+	for _, p := range c.phases {
+		events = append(events, traceEvent{
+			Name:      p.name,
+			Category:  "bee",
+			Phase:     "X",
+			Timestamp: p.start.UnixNano() / int64(time.Microsecond),
+			Duration:  int64(p.duration / time.Microsecond),
+			PID:       1,
+			TID:       tid,
+		})
+	}
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			beeLogger.Log.Errorf("Failed to encode the trace event: %s", err)
+			return
+		}
+		if _, err := trace.file.Write(append(line, ",\n"...)); err != nil {
+			beeLogger.Log.Errorf("Failed to write the trace: %s", err)
+			return
+		}
+	}
+}
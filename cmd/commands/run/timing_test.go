@@ -0,0 +1,56 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		durations := make([]time.Duration, len(values))
+		for i, v := range values {
+			durations[i] = time.Duration(v) * time.Millisecond
+		}
+		return durations
+	}
+	twenty := ms(20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1)
+
+	tests := []struct {
+		durations []time.Duration
+		p         int
+		want      time.Duration
+	}{
+		{nil, 50, 0},
+		{ms(7), 50, 7 * time.Millisecond},
+		{ms(7), 95, 7 * time.Millisecond},
+		{ms(4, 1, 3, 2), 50, 2 * time.Millisecond},
+		{ms(4, 1, 3, 2), 95, 4 * time.Millisecond},
+		{ms(4, 1, 3, 2), 0, 1 * time.Millisecond},
+		{ms(4, 1, 3, 2), 100, 4 * time.Millisecond},
+		{twenty, 50, 10 * time.Millisecond},
+		{twenty, 95, 19 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		before := append([]time.Duration(nil), tt.durations...)
+		if got := percentile(tt.durations, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %d) = %s, want %s", tt.durations, tt.p, got, tt.want)
+		}
+		if !reflect.DeepEqual(tt.durations, before) {
+			t.Errorf("percentile(%v, %d) reordered the durations", before, tt.p)
+		}
+	}
+}
@@ -16,15 +16,20 @@ package run
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"os"
 	"os/exec"
+	path "path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cisordeng/bee/config"
+	"github.com/cisordeng/bee/generate/swaggergen"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
@@ -34,8 +39,6 @@ import (
 var (
 	cmd                 *exec.Cmd
 	state               sync.Mutex
-	eventTime           = make(map[string]int64)
-	scheduleTime        time.Time
 	watchExts           = config.Conf.WatchExts
 	watchExtsStatic     = config.Conf.WatchExtsStatic
 	ignoredFilesRegExps = []string{
@@ -47,76 +50,193 @@ var (
 	}
 )
 
-// NewWatcher starts an fsnotify Watcher on the specified paths
+// builds tracks the latest build so that older ones can be cancelled
+var builds struct {
+	sync.Mutex
+	seq    uint64
+	cancel context.CancelFunc
+}
+
+// NewWatcher starts watching the specified paths, with fsnotify or by polling
 func NewWatcher(paths []string, files []string, isgenerate bool) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		beeLogger.Log.Fatalf("Failed to create watcher: %s", err)
-	}
+	events, errors := watchPaths(paths, isAppFile)
+	changes := newDebouncer(func(changed []string, build bool) {
+		runScheduledBuild(changed, build, files, isgenerate)
+	})
 
 	go func() {
 		for {
 			select {
-			case e := <-watcher.Events:
-				isBuild := true
-
+			case e := <-events:
 				if ifStaticFile(e.Name) && config.Conf.EnableReload {
 					sendReload(e.String())
 					continue
 				}
-				// Skip ignored files
-				if shouldIgnoreFile(e.Name) {
+				if isRestartEvent(e) {
+					beeLogger.Log.Hintf("Event fired: %s", e)
+					scheduleChange(changes, e.Name, false)
 					continue
 				}
-				if !shouldWatchFileWithExtension(e.Name) {
+				if !isBuildEvent(e) {
 					continue
 				}
 
-				mt := utils.GetFileModTime(e.Name)
-				if t := eventTime[e.Name]; mt == t {
-					beeLogger.Log.Hintf(colors.Bold("Skipping: ")+"%s", e.String())
-					isBuild = false
-				}
-
-				eventTime[e.Name] = mt
-
-				if isBuild {
-					beeLogger.Log.Hintf("Event fired: %s", e)
-					go func() {
-						// Wait 1s before autobuild until there is no file change.
-						scheduleTime = time.Now().Add(1 * time.Second)
-						time.Sleep(time.Until(scheduleTime))
-						AutoBuild(files, isgenerate)
-
-						if config.Conf.EnableReload {
-							// Wait 100ms more before refreshing the browser
-							time.Sleep(100 * time.Millisecond)
-							sendReload(e.String())
-						}
-					}()
-				}
-			case err := <-watcher.Errors:
+				beeLogger.Log.Hintf("Event fired: %s", e)
+				scheduleChange(changes, e.Name, true)
+			case err := <-errors:
 				beeLogger.Log.Warnf("Watcher error: %s", err.Error()) // No need to exit here
 			}
 		}
 	}()
+}
 
-	beeLogger.Log.Info("Initializing watcher...")
-	for _, path := range paths {
-		beeLogger.Log.Hintf(colors.Bold("Watching: ")+"%s", path)
-		err = watcher.Add(path)
-		if err != nil {
-			beeLogger.Log.Fatalf("Failed to watch directory: %s", err)
+// isBuildEvent reports whether the event is about a watched source file
+// which changed since the previous event. Files generated by editors are skipped.
+func isBuildEvent(e fsnotify.Event) bool {
+	return isChangeEvent(e, shouldWatchFileWithExtension)
+}
+
+// isRestartEvent reports whether the event is about a file
+// which only needs the application to be restarted
+func isRestartEvent(e fsnotify.Event) bool {
+	return isChangeEvent(e, ifRestartFile)
+}
+
+// isChangeEvent reports whether the event is about a file selected by
+// watched whose content changed since the previous event
+func isChangeEvent(e fsnotify.Event, watched func(string) bool) bool {
+	// Skip ignored files
+	if shouldIgnoreFile(e.Name) {
+		return false
+	}
+	if !watched(e.Name) {
+		return false
+	}
+	if isIgnored(e.Name) {
+		return false
+	}
+
+	if !contentChanged(e.Name) {
+		if verbose.Get() {
+			beeLogger.Log.Infof(colors.Bold("Unchanged: ")+"%s", e.String())
+		}
+		return false
+	}
+	return true
+}
+
+// scheduleChange records a changed file and (re)starts the quiet window.
+// The build only starts once no more events arrived during the window,
+// so bursts of events (e.g. a git checkout) result in a single build.
+// A build in progress is cancelled right away, since it is already stale.
+// The application is only restarted if none of the files needs a build.
+func scheduleChange(changes *debouncer, name string, build bool) {
+	if build {
+		cancelBuild()
+	}
+	changes.schedule(name, build)
+}
+
+// runScheduledBuild builds the application for the changes collected by scheduleChange
+func runScheduledBuild(changedPaths []string, build bool, files []string, isgenerate bool) {
+	changed := make([]string, 0, len(changedPaths))
+	docsChanged := false
+	for _, name := range changedPaths {
+		if isgenerate && !docsChanged {
+			docsChanged = swaggergen.IsDocsFile(currpath, name)
 		}
+		changed = append(changed, relPath(name))
+	}
+
+	if !build {
+		beeLogger.Log.Infof("Restarting after changes to: %s", strings.Join(changed, ", "))
+		if restartWithoutBuild() && config.Conf.EnableReload {
+			time.Sleep(100 * time.Millisecond)
+			sendReload(strings.Join(changed, ","))
+		}
+		return
+	}
+	beeLogger.Log.Infof("Rebuilding after changes to: %s", strings.Join(changed, ", "))
+
+	// The docs only depend on the routers and the controllers
+	if !AutoBuild(files, docsChanged) {
+		return
+	}
+	if runTests {
+		go testAffectedPackages(changedPaths)
+	}
+	if config.Conf.EnableReload {
+		// Wait 100ms more before refreshing the browser
+		time.Sleep(100 * time.Millisecond)
+		sendReload(strings.Join(changed, ","))
+	}
+}
+
+// newBuild cancels the build in progress, if any, and returns
+// the context and sequence number of a new build.
+func newBuild() (context.Context, uint64) {
+	builds.Lock()
+	defer builds.Unlock()
+
+	if builds.cancel != nil {
+		builds.cancel()
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	builds.cancel = cancel
+	builds.seq++
+	return ctx, builds.seq
 }
 
-// AutoBuild builds the specified set of files
-func AutoBuild(files []string, isgenerate bool) {
+// cancelBuild cancels the build in progress, if any. It can no longer
+// restart the application, even if it already went past "go build".
+func cancelBuild() {
+	builds.Lock()
+	defer builds.Unlock()
+
+	if builds.cancel != nil {
+		builds.cancel()
+		builds.cancel = nil
+	}
+	builds.seq++
+}
+
+// isLatestBuild reports whether no build was started after the build seq
+func isLatestBuild(seq uint64) bool {
+	builds.Lock()
+	defer builds.Unlock()
+	return builds.seq == seq
+}
+
+// AutoBuild builds the specified set of files.
+// Starting a build cancels the one in progress, and only the latest
+// build may restart the application. It returns true if it did so.
+func AutoBuild(files []string, isgenerate bool) bool {
+	ctx, seq := newBuild()
+
 	state.Lock()
 	defer state.Unlock()
 
+	// A newer build was started while waiting for the previous one
+	if ctx.Err() != nil {
+		return false
+	}
+
 	os.Chdir(currpath)
+	reportBuildStarted(appname)
+	c := newCycle(appname)
+	defer c.finish()
+
+	hooks := config.Conf.Hooks
+	if err := c.runHooks(ctx, "pre_build", hooks.PreBuild); err != nil {
+		if ctx.Err() != nil {
+			beeLogger.Log.Info("Build cancelled by newer changes")
+			c.discard()
+			return false
+		}
+		utils.Notify(err.Error(), "Build Failed")
+		beeLogger.Log.Errorf("Build cancelled: %s", err)
+		return false
+	}
 
 	cmdName := "go"
 
@@ -127,81 +247,167 @@ func AutoBuild(files []string, isgenerate bool) {
 	// For applications use full import path like "github.com/.../.."
 	// are able to use "go install" to reduce build time.
 	if config.Conf.GoInstall {
-		icmd := exec.Command(cmdName, "install", "-v")
+		icmd := exec.CommandContext(ctx, cmdName, "install", "-v")
 		icmd.Stdout = os.Stdout
 		icmd.Stderr = os.Stderr
 		icmd.Env = append(os.Environ(), "GOGC=off")
+		end := c.phase("go install")
 		icmd.Run()
+		end()
 	}
 
 	if isgenerate {
-		beeLogger.Log.Info("Generating the docs...")
-		icmd := exec.Command("bee", "generate", "docs")
-		icmd.Env = append(os.Environ(), "GOGC=off")
-		err = icmd.Run()
-		if err != nil {
-			utils.Notify("", "Failed to generate the docs.")
-			beeLogger.Log.Errorf("Failed to generate the docs.")
-			return
+		end := c.phase("docs")
+		generated := generateDocs()
+		end()
+		if !generated {
+			return false
 		}
-		beeLogger.Log.Success("Docs generated!")
 	}
-	appName := appname
+	appName := appBinaryName()
 	if err == nil {
-
-		if runtime.GOOS == "windows" {
-			appName += ".exe"
+		// With a health check the running binary is left untouched
+		// until the new one is known to start properly.
+		outName := appName
+		if healthCheckEnabled() {
+			outName = nextBinaryName(appName)
 		}
 
 		args := []string{"build"}
-		args = append(args, "-o", appName)
+		args = append(args, "-o", outName)
 		if buildTags != "" {
 			args = append(args, "-tags", buildTags)
 		}
 		args = append(args, files...)
 
-		bcmd := exec.Command(cmdName, args...)
+		bcmd := exec.CommandContext(ctx, cmdName, args...)
 		bcmd.Env = append(os.Environ(), "GOGC=off")
 		bcmd.Stderr = &stderr
+		end := c.phase("go build")
 		err = bcmd.Run()
+		end()
 		if err != nil {
+			if ctx.Err() != nil {
+				beeLogger.Log.Info("Build cancelled by newer changes")
+				c.discard()
+				return false
+			}
 			utils.Notify(stderr.String(), "Build Failed")
-			beeLogger.Log.Errorf("Failed to build the application: %s", stderr.String())
-			return
+			reportBuildFailed(appname, stderr.String())
+			return false
 		}
 	}
 
+	if !isLatestBuild(seq) {
+		c.discard()
+		return false
+	}
+
 	beeLogger.Log.Success("Built Successfully!")
+	reportBuildSucceeded(appname)
+	crashes.reset()
+	c.runHooksOrWarn(ctx, "post_build", hooks.PostBuild)
+
+	c.runHooksOrWarn(ctx, "pre_restart", hooks.PreRestart)
+	end := c.phase("restart")
+	if healthCheckEnabled() {
+		restarted := RestartWhenHealthy(appName, nextBinaryName(appName))
+		end()
+		if !restarted {
+			return false
+		}
+	} else {
+		Restart(appName)
+		end()
+	}
+	c.runHooksOrWarn(ctx, "post_restart", hooks.PostRestart)
+	return true
+}
+
+// generateDocs generates the docs of the application again.
+// It returns false if they could not be generated.
+func generateDocs() bool {
+	beeLogger.Log.Info("Generating the docs...")
+	written, err := swaggergen.RegenerateDocs(currpath)
+	if err != nil {
+		utils.Notify(err.Error(), "Failed to generate the docs.")
+		beeLogger.Log.Errorf("Failed to generate the docs: %s", err)
+		return false
+	}
+	if written {
+		beeLogger.Log.Success("Docs generated!")
+	} else {
+		beeLogger.Log.Info("Docs are up to date")
+	}
+	return true
+}
+
+// restartWithoutBuild restarts the application with its current binary.
+// It returns false if the application was not built yet.
+func restartWithoutBuild() bool {
+	state.Lock()
+	defer state.Unlock()
+
+	appName := appBinaryName()
+	if !utils.IsExist(path.Join(currpath, appName)) {
+		beeLogger.Log.Warnf("'%s' was not built yet, nothing to restart", appName)
+		return false
+	}
+
+	ctx := context.Background()
+	hooks := config.Conf.Hooks
+	c := newCycle(appname)
+	defer c.finish()
+	crashes.reset()
+	c.runHooksOrWarn(ctx, "pre_restart", hooks.PreRestart)
+	end := c.phase("restart")
 	Restart(appName)
+	end()
+	c.runHooksOrWarn(ctx, "post_restart", hooks.PostRestart)
+	return true
+}
+
+// appBinaryName returns the name of the binary of the application
+func appBinaryName() string {
+	if runtime.GOOS == "windows" {
+		return appname + ".exe"
+	}
+	return appname
 }
 
-// Kill kills the running command process
+// Kill kills the running command process.
+// Requests to the proxy are held until the application is started again.
 func Kill() {
+	holdRequests()
+	stopProcess(cmd)
+}
+
+// stopProcess interrupts the process of c and kills it
+// if it did not exit after 10 seconds
+func stopProcess(c *exec.Cmd) {
 	defer func() {
 		if e := recover(); e != nil {
 			beeLogger.Log.Infof("Kill recover: %s", e)
 		}
 	}()
-	if cmd != nil && cmd.Process != nil {
+	if c != nil && c.Process != nil {
+		pe := exitOf(c)
+		defer exits.Delete(c)
+		atomic.StoreInt32(&pe.stopping, 1)
+
 		// Windows does not support Interrupt
 		if runtime.GOOS == "windows" {
-			cmd.Process.Signal(os.Kill)
+			c.Process.Signal(os.Kill)
 		} else {
-			cmd.Process.Signal(os.Interrupt)
+			c.Process.Signal(os.Interrupt)
 		}
 
-		ch := make(chan struct{}, 1)
-		go func() {
-			cmd.Wait()
-			ch <- struct{}{}
-		}()
-
 		select {
-		case <-ch:
+		case <-pe.done:
 			return
 		case <-time.After(10 * time.Second):
 			beeLogger.Log.Info("Timeout. Force kill cmd process")
-			err := cmd.Process.Kill()
+			err := c.Process.Kill()
 			if err != nil {
 				beeLogger.Log.Errorf("Error while killing cmd process: %s", err)
 			}
@@ -214,7 +420,7 @@ func Kill() {
 func Restart(appname string) {
 	beeLogger.Log.Debugf("Kill running process", utils.FILE(), utils.LINE())
 	Kill()
-	go Start(appname)
+	Start(appname)
 }
 
 // Start starts the command process
@@ -224,21 +430,63 @@ func Start(appname string) {
 		appname = "./" + appname
 	}
 
-	cmd = exec.Command(appname)
+	cmd = newAppCmd(appname)
+	tail := newTailWriter(config.Conf.Crash.LogLines)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = io.MultiWriter(os.Stderr, tail)
+
+	if err := cmd.Start(); err != nil {
+		beeLogger.Log.Errorf("Failed to start '%s': %s", appname, err)
+		resumeRequests()
+		return
+	}
+	go watchAppCrash(cmd, appname, tail)
+	resumeRequests()
+	beeLogger.Log.Successf("'%s' is running...", appname)
+	select {
+	case started <- true:
+	default:
+	}
+}
+
+// newAppCmd prepares the command running the application binary
+// with the configured arguments and environment variables
+func newAppCmd(appname string) *exec.Cmd {
+	c := exec.Command(appname)
 	if runargs != "" {
 		r := regexp.MustCompile("'.+'|\".+\"|\\S+")
 		m := r.FindAllString(runargs, -1)
-		cmd.Args = append([]string{appname}, m...)
+		c.Args = append([]string{appname}, m...)
 	} else {
-		cmd.Args = append([]string{appname}, config.Conf.CmdArgs...)
+		c.Args = append([]string{appname}, config.Conf.CmdArgs...)
 	}
-	cmd.Env = append(os.Environ(), config.Conf.Envs...)
+	c.Env = appEnv()
+	return c
+}
 
-	go cmd.Run()
-	beeLogger.Log.Successf("'%s' is running...", appname)
-	started <- true
+// appEnv returns the environment of the application: the environment of bee,
+// the variables of the .env files, then the envs of the Beefile.
+// The .env files are read again on each call so that a restart applies their changes.
+func appEnv() []string {
+	dotenv, err := utils.LoadEnvFiles(currpath, os.Getenv("BEEGO_RUNMODE"))
+	if err != nil {
+		beeLogger.Log.Warnf("Failed to load the .env files: %s", err)
+	}
+	env := append(os.Environ(), dotenv...)
+	return append(env, config.Conf.Envs...)
+}
+
+// ifRestartFile reports whether a change to the file only needs the application to be restarted
+func ifRestartFile(filename string) bool {
+	if utils.IsEnvFile(filename) {
+		return true
+	}
+	for _, s := range config.Conf.WatchExtsRestart {
+		if strings.HasSuffix(filename, s) {
+			return true
+		}
+	}
+	return false
 }
 
 func ifStaticFile(filename string) bool {
@@ -0,0 +1,336 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package run
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	path "path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisordeng/bee/cmd/commands"
+	"github.com/cisordeng/bee/cmd/commands/version"
+	"github.com/cisordeng/bee/config"
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+	"github.com/cisordeng/bee/utils"
+	"github.com/fsnotify/fsnotify"
+)
+
+var CmdWatch = &commands.Command{
+	UsageLine: "watch [-p=pattern] [-startup] [-- command] | [watcher...]",
+	Short:     "Run commands when files change",
+	Long: `
+Watch command runs a command each time the files matching one of its gitignore-style
+patterns change, e.g. to regenerate the protobuf code or to bundle the frontend:
+
+  $ bee watch -p "proto/**/*.proto" -- make proto
+
+A single argument after '--' is run by the shell, several are run as they are given,
+without a shell, e.g. -- sh -c "protoc *.proto && go generate ./...".
+
+Without -p, it runs the 'watchers' of the Beefile, or only those whose names are given.
+Each watcher has a 'name', 'patterns', a 'command', which is either an inline command
+or the name of a script of the Beefile, and 'startup' to run the command once at startup.
+
+The paths ignored by "bee run", the hidden directories and node_modules are not watched,
+unless a pattern starts with one of them, like -p "docs/**". The directories created
+later are watched, and the command runs once the changes have settled for 'build_delay'
+milliseconds.
+`,
+	PreRun: func(cmd *commands.Command, args []string) { version.ShowShortVersionBanner() },
+	Run:    RunWatchers,
+}
+
+var (
+	// Patterns of the files watched for the command given on the command line
+	watchCmdPatterns utils.StrFlags
+	// Flag to run the command given on the command line once at startup
+	watchCmdStartup bool
+)
+
+func init() {
+	CmdWatch.Flag.Var(&watchCmdPatterns, "p", "Gitignore-style pattern of the files to watch, e.g. -p=\"proto/**/*.proto\". Can be repeated.")
+	CmdWatch.Flag.BoolVar(&watchCmdStartup, "startup", false, "Run the command once at startup.")
+	CmdWatch.Flag.Var(&verbose, "verbose", "Log the events of files whose content did not change.")
+	commands.AvailableCommands = append(commands.AvailableCommands, CmdWatch)
+}
+
+// commandWatcher runs a command when the files matching its patterns change
+type commandWatcher struct {
+	name     string
+	command  string   // Command line run by the shell, if args is empty
+	args     []string // Program and arguments run without a shell
+	patterns []string
+	matcher  *utils.IgnoreMatcher // Matches the watched files
+	startup  bool                 // Whether to run the command once at startup
+	prefix   string               // Colored prefix of the output lines
+
+	mu sync.Mutex // Held while the command runs
+
+	changes *debouncer // Changed files waiting for the quiet window to elapse
+}
+
+// RunWatchers watches the application directory and runs the commands of the
+// watchers whose files changed
+func RunWatchers(cmd *commands.Command, args []string) int {
+	beeLogger.Log.SetVerbose(verbose.Get())
+	currpath, _ = os.Getwd()
+	watchers := newCommandWatchers(args)
+	if len(watchers) == 0 {
+		beeLogger.Log.Fatal("No watcher to run. Use -p with a command, or add 'watchers' to the Beefile.")
+	}
+	watchMatcher = utils.LoadIgnoreMatcher(currpath, watcherExcludes(watchers), config.Conf.Watch.Exclude, config.Conf.Watch.Include)
+
+	isWatchedFile := func(name string) bool {
+		if shouldIgnoreFile(name) || isIgnored(name) {
+			return false
+		}
+		rel, err := path.Rel(currpath, name)
+		if err != nil {
+			return false
+		}
+		for _, w := range watchers {
+			if w.matcher.Match(rel, false) {
+				return true
+			}
+		}
+		return false
+	}
+
+	dirs := readAllDirectories(currpath)
+	indexFileHashes(dirs, isWatchedFile)
+	events, errors, watchDir := watchDirs(dirs, isWatchedFile)
+
+	for _, w := range watchers {
+		beeLogger.Log.Infof("Watching %s for '%s'", strings.Join(w.patterns, ", "), w.name)
+		if w.startup {
+			go w.run(nil)
+		}
+	}
+
+	for {
+		select {
+		case e := <-events:
+			if e.Op&fsnotify.Create != 0 && isNewDirectory(e.Name) {
+				// Watch the new directories, and the files already created in them
+				for _, dir := range readAllDirectories(e.Name) {
+					watchDir(dir)
+					fileInfos, _ := ioutil.ReadDir(dir)
+					for _, fileInfo := range fileInfos {
+						if !fileInfo.IsDir() {
+							dispatchChange(watchers, isWatchedFile, fsnotify.Event{Name: path.Join(dir, fileInfo.Name()), Op: fsnotify.Create})
+						}
+					}
+				}
+				continue
+			}
+			dispatchChange(watchers, isWatchedFile, e)
+		case err := <-errors:
+			beeLogger.Log.Warnf("Watcher error: %s", err.Error()) // No need to exit here
+		}
+	}
+}
+
+// newCommandWatchers creates the watcher of the command given on the command line
+// if there is one, otherwise the watchers of the Beefile whose names are in args,
+// or all of them if args is empty
+func newCommandWatchers(args []string) []*commandWatcher {
+	if len(watchCmdPatterns) > 0 {
+		if len(args) == 0 {
+			beeLogger.Log.Fatal("No command to run. Give it after '--', e.g. bee watch -p \"*.proto\" -- make proto")
+		}
+		w := newCommandWatcher("watch", args[0], watchCmdPatterns, watchCmdStartup, 0, 0)
+		if len(args) > 1 {
+			// Keep the arguments as they were quoted, e.g. sh -c "go test ./..."
+			w.command = strings.Join(args, " ")
+			w.args = args
+		}
+		return []*commandWatcher{w}
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range args {
+		selected[name] = true
+	}
+	var (
+		confs []int
+		width int
+	)
+	for i, conf := range config.Conf.Watchers {
+		if conf.Name == "" {
+			config.Conf.Watchers[i].Name = fmt.Sprintf("watcher%d", i+1)
+		}
+		name := config.Conf.Watchers[i].Name
+		if len(args) > 0 && !selected[name] {
+			continue
+		}
+		delete(selected, name)
+		if len(name) > width {
+			width = len(name)
+		}
+		confs = append(confs, i)
+	}
+	for name := range selected {
+		beeLogger.Log.Fatalf("No watcher '%s' found in the Beefile", name)
+	}
+
+	var watchers []*commandWatcher
+	for n, i := range confs {
+		conf := config.Conf.Watchers[i]
+		if conf.Command == "" || len(conf.Patterns) == 0 {
+			beeLogger.Log.Warnf("Watcher '%s' needs 'patterns' and a 'command', skipping it", conf.Name)
+			continue
+		}
+		watchers = append(watchers, newCommandWatcher(conf.Name, conf.Command, conf.Patterns, conf.Startup, n, width))
+	}
+	return watchers
+}
+
+func newCommandWatcher(name, command string, patterns []string, startup bool, index, width int) *commandWatcher {
+	if script, ok := config.Conf.Scripts[command]; ok {
+		command = script
+	}
+	color := prefixColors[index%len(prefixColors)]
+	w := &commandWatcher{
+		name:     name,
+		command:  command,
+		patterns: patterns,
+		matcher:  utils.NewIgnoreMatcher(patterns...),
+		startup:  startup,
+		prefix:   color(fmt.Sprintf("%-*s |", width, name)) + " ",
+	}
+	w.changes = newDebouncer(func(changed []string, _ bool) { w.run(changed) })
+	return w
+}
+
+// watcherExcludes returns the patterns of the paths not watched by default: those
+// never watched by "bee run" and node_modules. A default is dropped if a pattern
+// of a watcher starts with the directory it excludes, e.g. -p "docs/**".
+func watcherExcludes(watchers []*commandWatcher) []string {
+	var excludes []string
+	for _, exclude := range append(defaultWatchExcludes(), "node_modules") {
+		targeted := false
+		for _, w := range watchers {
+			for _, pattern := range w.patterns {
+				dir := strings.SplitN(strings.TrimPrefix(pattern, "/"), "/", 2)[0]
+				if ok, _ := path.Match(exclude, dir); ok {
+					targeted = true
+				}
+			}
+		}
+		if !targeted {
+			excludes = append(excludes, exclude)
+		}
+	}
+	return excludes
+}
+
+// dispatchChange schedules the commands of the watchers matching a changed file
+func dispatchChange(watchers []*commandWatcher, isWatchedFile func(string) bool, e fsnotify.Event) {
+	if e.Op == fsnotify.Chmod || !isWatchedFile(e.Name) {
+		return
+	}
+	if !contentChanged(e.Name) {
+		if verbose.Get() {
+			beeLogger.Log.Infof(colors.Bold("Unchanged: ")+"%s", e.String())
+		}
+		return
+	}
+
+	beeLogger.Log.Hintf("Event fired: %s", e)
+	rel, _ := path.Rel(currpath, e.Name)
+	for _, w := range watchers {
+		if w.matcher.Match(rel, false) {
+			w.changes.schedule(rel, false)
+		}
+	}
+}
+
+// isNewDirectory reports whether a created path is a directory to watch
+func isNewDirectory(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir() && !isSkippedDirectory(name)
+}
+
+// readAllDirectories returns root and its subdirectories, except the hidden and
+// ignored ones, whose content is not walked
+func readAllDirectories(root string) []string {
+	var dirs []string
+	path.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if p != root && isSkippedDirectory(p) {
+			return path.SkipDir
+		}
+		dirs = append(dirs, p)
+		return nil
+	})
+	return dirs
+}
+
+// isSkippedDirectory reports whether a directory of the application is hidden,
+// like .git, or matches the patterns of the paths not to watch, like node_modules
+func isSkippedDirectory(dir string) bool {
+	if strings.HasPrefix(path.Base(dir), ".") {
+		return true
+	}
+	rel, err := path.Rel(currpath, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return watchMatcher.Match(rel, true)
+}
+
+// run runs the command with its output prefixed by the name of the watcher.
+// A run waits for the previous one to end.
+func (w *commandWatcher) run(changed []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(changed) > 0 {
+		beeLogger.Log.Infof("Running '%s' after changes to: %s", w.name, strings.Join(changed, ", "))
+	} else {
+		beeLogger.Log.Infof("Running '%s'...", w.name)
+	}
+	stdout := &prefixWriter{w: colors.NewColorWriter(os.Stdout), prefix: w.prefix}
+	stderr := &prefixWriter{w: colors.NewColorWriter(os.Stderr), prefix: w.prefix}
+
+	start := time.Now()
+	var c *exec.Cmd
+	if len(w.args) > 0 {
+		c = exec.Command(w.args[0], w.args[1:]...)
+	} else {
+		c = utils.ShellCommand(context.Background(), w.command)
+	}
+	c.Dir = currpath
+	c.Env = appEnv()
+	c.Stdout = stdout
+	c.Stderr = stderr
+	err := c.Run()
+	stdout.flush()
+	stderr.flush()
+	if err != nil {
+		utils.Notify(err.Error(), fmt.Sprintf("'%s' Failed", w.name))
+		beeLogger.Log.Errorf("'%s' failed: %s", w.name, err)
+		return
+	}
+	beeLogger.Log.Successf("'%s' finished in %s", w.name, formatDuration(time.Since(start)))
+}
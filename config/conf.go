@@ -29,19 +29,31 @@ var Conf = struct {
 	Version            int
 	WatchExts          []string  `json:"watch_ext" yaml:"watch_ext"`
 	WatchExtsStatic    []string  `json:"watch_ext_static" yaml:"watch_ext_static"`
-	GoInstall          bool      `json:"go_install" yaml:"go_install"` // Indicates whether execute "go install" before "go build".
+	WatchExtsRestart   []string  `json:"watch_ext_restart" yaml:"watch_ext_restart"`
+	GoInstall          bool      `json:"go_install" yaml:"go_install"`   // Indicates whether execute "go install" before "go build".
+	BuildDelay         int       `json:"build_delay" yaml:"build_delay"` // Quiet window in milliseconds to wait for more changes before building.
+	Poll               int       `json:"poll" yaml:"poll"`               // Interval in milliseconds to scan for changes instead of using filesystem events.
 	DirStruct          dirStruct `json:"dir_structure" yaml:"dir_structure"`
 	CmdArgs            []string  `json:"cmd_args" yaml:"cmd_args"`
 	Envs               []string
 	Bale               bale
 	Database           database
-	EnableReload       bool              `json:"enable_reload" yaml:"enable_reload"`
-	EnableNotification bool              `json:"enable_notification" yaml:"enable_notification"`
-	Scripts            map[string]string `json:"scripts" yaml:"scripts"`
+	EnableReload       bool               `json:"enable_reload" yaml:"enable_reload"`
+	EnableNotification bool               `json:"enable_notification" yaml:"enable_notification"`
+	Scripts            map[string]string  `json:"scripts" yaml:"scripts"`
+	HealthCheck        healthCheck        `json:"health_check" yaml:"health_check"`
+	Processes          []process          `json:"processes" yaml:"processes"`
+	Watch              watchPatterns      `json:"watch" yaml:"watch"`
+	Crash              crashRestart       `json:"crash" yaml:"crash"`
+	Hooks              hooks              `json:"hooks" yaml:"hooks"`
+	Profiles           map[string]profile `json:"profiles" yaml:"profiles"`
+	Watchers           []watcher          `json:"watchers" yaml:"watchers"`
 }{
-	WatchExts:       []string{".go"},
-	WatchExtsStatic: []string{".html", ".tpl", ".js", ".css"},
-	GoInstall:       true,
+	WatchExts:        []string{".go"},
+	WatchExtsStatic:  []string{".html", ".tpl", ".js", ".css"},
+	WatchExtsRestart: []string{".conf"},
+	GoInstall:        true,
+	BuildDelay:       1000,
 	DirStruct: dirStruct{
 		Others: []string{},
 	},
@@ -56,6 +68,15 @@ var Conf = struct {
 	},
 	EnableNotification: true,
 	Scripts:            map[string]string{},
+	Profiles:           map[string]profile{},
+	HealthCheck: healthCheck{
+		Timeout: 10000,
+	},
+	Crash: crashRestart{
+		MaxRestarts: 5,
+		Backoff:     1000,
+		LogLines:    20,
+	},
 }
 
 // dirStruct describes the application's directory structure
@@ -80,6 +101,64 @@ type database struct {
 	Dir    string
 }
 
+// healthCheck describes how "bee run" tells that a new build is ready to serve
+type healthCheck struct {
+	URL      string // HTTP URL answering with a 2xx or 3xx status once ready
+	TCP      string // Address accepting TCP connections once ready
+	Timeout  int    // Milliseconds to wait for the check to pass
+	PortEnv  string `json:"port_env" yaml:"port_env"`   // Environment variable the application reads its port from
+	SidePort int    `json:"side_port" yaml:"side_port"` // Port to probe a new build on before stopping the running one
+}
+
+// process describes one of the processes supervised together by "bee run"
+type process struct {
+	Name  string
+	Main  string   // Main package or file to build, defaults to the application directory
+	Args  []string // Arguments passed to the process
+	Envs  []string // Environment variables added to the global ones
+	Watch []string // Paths watched for changes, defaults to the whole application
+}
+
+// crashRestart describes how "bee run" restarts an application which exited on its own
+type crashRestart struct {
+	MaxRestarts int `json:"max_restarts" yaml:"max_restarts"` // Restarts in a row before waiting for changes
+	Backoff     int // Milliseconds to wait before the first restart, doubled on each restart
+	LogLines    int `json:"log_lines" yaml:"log_lines"` // Number of lines of stderr shown after a crash
+}
+
+// hooks lists the scripts, or inline commands, run by "bee run" around builds and restarts
+type hooks struct {
+	PreBuild    []string `json:"pre_build" yaml:"pre_build"` // A failing pre-build hook cancels the build
+	PostBuild   []string `json:"post_build" yaml:"post_build"`
+	PreRestart  []string `json:"pre_restart" yaml:"pre_restart"`
+	PostRestart []string `json:"post_restart" yaml:"post_restart"`
+}
+
+// profile bundles the settings of "bee run" selected with -profile.
+// The flags given on the command line take precedence.
+type profile struct {
+	Runmode string
+	Tags    string   // Build tags
+	Main    []string // Main files to build
+	Args    []string // Arguments passed to the application, instead of cmd_args
+	Envs    []string // Environment variables added to the global ones
+	Exclude []string // Paths excluded from watching
+}
+
+// watcher describes a command run by "bee watch" when the files matching its patterns change
+type watcher struct {
+	Name     string
+	Patterns []string // Gitignore-style patterns of the watched files
+	Command  string   // Name of a script of the Beefile, or inline command
+	Startup  bool     // Whether to run the command once at startup
+}
+
+// watchPatterns holds gitignore-style patterns, added to those of the .beeignore file
+type watchPatterns struct {
+	Include []string // Paths included again after being excluded
+	Exclude []string // Paths excluded from watching and packing
+}
+
 // LoadConfig loads the bee tool configuration.
 // It looks for Beefile or bee.json in the current path,
 // and falls back to default configuration in case not found.
@@ -19,6 +19,11 @@ import "github.com/cisordeng/bee/utils"
 var SQLDriver utils.DocValue
 var SQLConn utils.DocValue
 var Level utils.DocValue
+var Layout utils.DocValue
 var Tables utils.DocValue
 var Fields utils.DocValue
 var DDL utils.DocValue
+var CRUD bool
+var DryRun bool
+var Diff bool
+var Force bool
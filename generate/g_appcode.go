@@ -24,7 +24,6 @@ import (
 	"strings"
 
 	beeLogger "github.com/cisordeng/bee/logger"
-	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -34,6 +33,7 @@ const (
 	OModel byte = 1 << iota
 	OController
 	ORouter
+	OXenon // rest, business and model layers of xenon resources
 )
 
 // DbTransformer has method to reverse engineer a database schema to restful api code
@@ -204,6 +204,18 @@ func (col *Column) String() string {
 
 // String returns the ORM tag string for a column
 func (tag *OrmTag) String() string {
+	ormOptions := tag.options()
+	if len(ormOptions) == 0 {
+		return ""
+	}
+	if tag.Comment != "" {
+		return fmt.Sprintf("`orm:\"%s\" description:\"%s\"`", strings.Join(ormOptions, ";"), tag.Comment)
+	}
+	return fmt.Sprintf("`orm:\"%s\"`", strings.Join(ormOptions, ";"))
+}
+
+// options returns the options of the ORM tag of a column, e.g. size(64)
+func (tag *OrmTag) options() []string {
 	var ormOptions []string
 	if tag.Column != "" {
 		ormOptions = append(ormOptions, fmt.Sprintf("column(%s)", tag.Column))
@@ -253,17 +265,10 @@ func (tag *OrmTag) String() string {
 	if tag.Default != "" {
 		ormOptions = append(ormOptions, fmt.Sprintf("default(%s)", tag.Default))
 	}
-
-	if len(ormOptions) == 0 {
-		return ""
-	}
-	if tag.Comment != "" {
-		return fmt.Sprintf("`orm:\"%s\" description:\"%s\"`", strings.Join(ormOptions, ";"), tag.Comment)
-	}
-	return fmt.Sprintf("`orm:\"%s\"`", strings.Join(ormOptions, ";"))
+	return ormOptions
 }
 
-func GenerateAppcode(driver, connStr, level, tables, currpath string) {
+func GenerateAppcode(driver, connStr, level, layout, tables, currpath string) {
 	var mode byte
 	switch level {
 	case "1":
@@ -275,6 +280,14 @@ func GenerateAppcode(driver, connStr, level, tables, currpath string) {
 	default:
 		beeLogger.Log.Fatal("Invalid level value. Must be either \"1\", \"2\", or \"3\"")
 	}
+	switch layout {
+	case "beego":
+	case "xenon":
+		// The rest, business and model layers of a resource are always generated together
+		mode = OXenon
+	default:
+		beeLogger.Log.Fatal("Invalid layout value. Must be either \"beego\" or \"xenon\"")
+	}
 	var selectedTables map[string]bool
 	if tables != "" {
 		selectedTables = make(map[string]bool)
@@ -312,13 +325,16 @@ func gen(dbms, connStr string, mode byte, selectedTableNames map[string]bool, ap
 			tableNames = trans.GetTableNames(db)
 		}
 		tables := getTableObjects(tableNames, db, trans)
+		if mode == OXenon {
+			writeTableResources(tables, apppath)
+			return
+		}
 		mvcPath := new(MvcPath)
 		mvcPath.ModelPath = path.Join(apppath, "models")
 		mvcPath.ControllerPath = path.Join(apppath, "controllers")
 		mvcPath.RouterPath = path.Join(apppath, "routers")
-		createPaths(mode, mvcPath)
 		pkgPath := getPackagePath(apppath)
-		writeSourceFiles(pkgPath, tables, mode, mvcPath)
+		writeSourceFiles(apppath, pkgPath, tables, mode, mvcPath)
 	} else {
 		beeLogger.Log.Fatalf("Generating app code from '%s' database is not supported yet.", dbms)
 	}
@@ -712,180 +728,75 @@ func (*PostgresDB) GetGoDataType(sqlType string) (string, error) {
 	return "", fmt.Errorf("data type '%s' not found", sqlType)
 }
 
-// deleteAndRecreatePaths removes several directories completely
-func createPaths(mode byte, paths *MvcPath) {
-	if (mode & OModel) == OModel {
-		os.Mkdir(paths.ModelPath, 0777)
-	}
-	if (mode & OController) == OController {
-		os.Mkdir(paths.ControllerPath, 0777)
-	}
-	if (mode & ORouter) == ORouter {
-		os.Mkdir(paths.RouterPath, 0777)
-	}
-}
-
 // writeSourceFiles generates source files for model/controller/router
-// It will wipe the following directories and recreate them:./models, ./controllers, ./routers
-// Newly geneated files will be inside these folders.
-func writeSourceFiles(pkgPath string, tables []*Table, mode byte, paths *MvcPath) {
+// in the following directories: ./models, ./controllers, ./routers
+func writeSourceFiles(apppath, pkgPath string, tables []*Table, mode byte, paths *MvcPath) {
+	plan := &Plan{}
 	if (OModel & mode) == OModel {
 		beeLogger.Log.Info("Creating model files...")
-		writeModelFiles(tables, paths.ModelPath)
+		addModelFiles(plan, apppath, tables, paths.ModelPath)
 	}
 	if (OController & mode) == OController {
 		beeLogger.Log.Info("Creating controller files...")
-		writeControllerFiles(tables, paths.ControllerPath, pkgPath)
+		addControllerFiles(plan, apppath, tables, paths.ControllerPath, pkgPath)
 	}
 	if (ORouter & mode) == ORouter {
 		beeLogger.Log.Info("Creating router files...")
-		writeRouterFile(tables, paths.RouterPath, pkgPath)
+		addRouterFile(plan, apppath, tables, paths.RouterPath, pkgPath)
 	}
+	plan.Apply()
 }
 
-// writeModelFiles generates model files
-func writeModelFiles(tables []*Table, mPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
+// addModelFiles plans the model files
+func addModelFiles(plan *Plan, apppath string, tables []*Table, mPath string) {
 	for _, tb := range tables {
 		filename := getFileName(tb.Name)
 		fpath := path.Join(mPath, filename+".go")
-		var f *os.File
-		var err error
-		if utils.IsExist(fpath) {
-			beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-			if utils.AskForConfirmation() {
-				f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-				if err != nil {
-					beeLogger.Log.Warnf("%s", err)
-					continue
-				}
-			} else {
-				beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-				continue
-			}
-		} else {
-			f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				continue
-			}
-		}
-		var template string
+		template := ModelTPL
 		if tb.Pk == "" {
 			template = StructModelTPL
-		} else {
-			template = ModelTPL
-		}
-		fileStr := strings.Replace(template, "{{modelStruct}}", tb.String(), 1)
-		fileStr = strings.Replace(fileStr, "{{modelName}}", utils.CamelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{tableName}}", tb.Name, -1)
-
-		// If table contains time field, import time.Time package
-		timePkg := ""
-		importTimePkg := ""
-		if tb.ImportTimePkg {
-			timePkg = "\"time\"\n"
-			importTimePkg = "import \"time\"\n"
 		}
-		fileStr = strings.Replace(fileStr, "{{timePkg}}", timePkg, -1)
-		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Fatalf("Could not write model file to '%s': %s", fpath, err)
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		plan.Add(fpath, template.Render(apppath, map[string]interface{}{
+			"modelStruct": tb.String(),
+			"modelName":   utils.CamelCase(tb.Name),
+			"tableName":   tb.Name,
+			"importTime":  tb.ImportTimePkg,
+		}))
 	}
 }
 
-// writeControllerFiles generates controller files
-func writeControllerFiles(tables []*Table, cPath string, pkgPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
+// addControllerFiles plans the controller files
+func addControllerFiles(plan *Plan, apppath string, tables []*Table, cPath string, pkgPath string) {
 	for _, tb := range tables {
 		if tb.Pk == "" {
 			continue
 		}
 		filename := getFileName(tb.Name)
 		fpath := path.Join(cPath, filename+".go")
-		var f *os.File
-		var err error
-		if utils.IsExist(fpath) {
-			beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-			if utils.AskForConfirmation() {
-				f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-				if err != nil {
-					beeLogger.Log.Warnf("%s", err)
-					continue
-				}
-			} else {
-				beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-				continue
-			}
-		} else {
-			f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				continue
-			}
-		}
-		fileStr := strings.Replace(CtrlTPL, "{{ctrlName}}", utils.CamelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{pkgPath}}", pkgPath, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Fatalf("Could not write controller file to '%s': %s", fpath, err)
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		plan.Add(fpath, CtrlTPL.Render(apppath, map[string]interface{}{
+			"ctrlName": utils.CamelCase(tb.Name),
+			"pkgPath":  pkgPath,
+		}))
 	}
 }
 
-// writeRouterFile generates router file
-func writeRouterFile(tables []*Table, rPath string, pkgPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
-	var nameSpaces []string
+// addRouterFile plans the router file
+func addRouterFile(plan *Plan, apppath string, tables []*Table, rPath string, pkgPath string) {
+	var nameSpaces []map[string]string
 	for _, tb := range tables {
 		if tb.Pk == "" {
 			continue
 		}
-		// Add namespaces
-		nameSpace := strings.Replace(NamespaceTPL, "{{nameSpace}}", tb.Name, -1)
-		nameSpace = strings.Replace(nameSpace, "{{ctrlName}}", utils.CamelCase(tb.Name), -1)
-		nameSpaces = append(nameSpaces, nameSpace)
+		nameSpaces = append(nameSpaces, map[string]string{
+			"nameSpace": tb.Name,
+			"ctrlName":  utils.CamelCase(tb.Name),
+		})
 	}
-	// Add export controller
 	fpath := filepath.Join(rPath, "router.go")
-	routerStr := strings.Replace(RouterTPL, "{{nameSpaces}}", strings.Join(nameSpaces, ""), 1)
-	routerStr = strings.Replace(routerStr, "{{pkgPath}}", pkgPath, 1)
-	var f *os.File
-	var err error
-	if utils.IsExist(fpath) {
-		beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-		if utils.AskForConfirmation() {
-			f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				return
-			}
-		} else {
-			beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-			return
-		}
-	} else {
-		f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-		if err != nil {
-			beeLogger.Log.Warnf("%s", err)
-			return
-		}
-	}
-	if _, err := f.WriteString(routerStr); err != nil {
-		beeLogger.Log.Fatalf("Could not write router file to '%s': %s", fpath, err)
-	}
-	utils.CloseFile(f)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-	utils.FormatSourceCode(fpath)
+	plan.Add(fpath, RouterTPL.Render(apppath, map[string]interface{}{
+		"nameSpaces": nameSpaces,
+		"pkgPath":    pkgPath,
+	}))
 }
 
 func isSQLTemporalType(t string) bool {
@@ -946,6 +857,12 @@ func getFileName(tbName string) (filename string) {
 }
 
 func getPackagePath(curpath string) (packpath string) {
+	if found, _, modPath := utils.GetModule(curpath); found {
+		beeLogger.Log.Debugf("Module: %s", utils.FILE(), utils.LINE(), modPath)
+		packpath, _ = utils.GetImportPath(curpath)
+		return
+	}
+
 	gopath := os.Getenv("GOPATH")
 	if gopath == "" {
 		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
@@ -978,58 +895,79 @@ func getPackagePath(curpath string) (packpath string) {
 	return
 }
 
-const (
-	StructModelTPL = `package models
-{{importTimePkg}}
-{{modelStruct}}
+// appcodeTemplateDoc describes the data of the appcode templates
+const appcodeTemplateDoc = `
+Data of the templates of "bee generate appcode" with the beego layout, for each table:
+
+  .modelStruct  Struct of the model, with its fields and orm tags (struct_model, model)
+  .modelName    Name of the model, e.g. UserAccount for the table user_account (struct_model, model)
+  .tableName    Name of the table, e.g. user_account (struct_model, model)
+  .importTime   Whether the model has time fields (struct_model, model)
+  .ctrlName     Name of the controller, e.g. UserAccount (controller)
+  .pkgPath      Import path of the application (controller, router)
+  .nameSpaces   Namespaces of the router, each with .nameSpace, the table name,
+                and .ctrlName (router)
+
+struct_model is used for the tables without primary key, model for the others.
 `
 
-	ModelTPL = `package models
+var (
+	StructModelTPL = NewTemplate("appcode", "struct_model", appcodeTemplateDoc, `package models
+{{if .importTime}}
+import "time"
+{{end}}
+{{.modelStruct}}
+`)
+
+	ModelTPL = NewTemplate("appcode", "model", appcodeTemplateDoc, `package models
 
 import (
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
-	{{timePkg}}
+{{- if .importTime}}
+	"time"
+{{- end}}
+
 	"github.com/cisordeng/beego/orm"
 )
 
-{{modelStruct}}
+{{.modelStruct}}
 
-func (t *{{modelName}}) TableName() string {
-	return "{{tableName}}"
+func (t *{{.modelName}}) TableName() string {
+	return "{{.tableName}}"
 }
 
 func init() {
-	orm.RegisterModel(new({{modelName}}))
+	orm.RegisterModel(new({{.modelName}}))
 }
 
-// Add{{modelName}} insert a new {{modelName}} into database and returns
+// Add{{.modelName}} insert a new {{.modelName}} into database and returns
 // last inserted Id on success.
-func Add{{modelName}}(m *{{modelName}}) (id int64, err error) {
+func Add{{.modelName}}(m *{{.modelName}}) (id int64, err error) {
 	o := orm.NewOrm()
 	id, err = o.Insert(m)
 	return
 }
 
-// Get{{modelName}}ById retrieves {{modelName}} by Id. Returns error if
+// Get{{.modelName}}ById retrieves {{.modelName}} by Id. Returns error if
 // Id doesn't exist
-func Get{{modelName}}ById(id int) (v *{{modelName}}, err error) {
+func Get{{.modelName}}ById(id int) (v *{{.modelName}}, err error) {
 	o := orm.NewOrm()
-	v = &{{modelName}}{Id: id}
+	v = &{{.modelName}}{Id: id}
 	if err = o.Read(v); err == nil {
 		return v, nil
 	}
 	return nil, err
 }
 
-// GetAll{{modelName}} retrieves all {{modelName}} matches certain condition. Returns empty list if
+// GetAll{{.modelName}} retrieves all {{.modelName}} matches certain condition. Returns empty list if
 // no records exist
-func GetAll{{modelName}}(query map[string]string, fields []string, sortby []string, order []string,
+func GetAll{{.modelName}}(query map[string]string, fields []string, sortby []string, order []string,
 	offset int64, limit int64) (ml []interface{}, err error) {
 	o := orm.NewOrm()
-	qs := o.QueryTable(new({{modelName}}))
+	qs := o.QueryTable(new({{.modelName}}))
 	// query k=v
 	for k, v := range query {
 		// rewrite dot-notation to Object__Attribute
@@ -1079,7 +1017,7 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 		}
 	}
 
-	var l []{{modelName}}
+	var l []{{.modelName}}
 	qs = qs.OrderBy(sortFields...)
 	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
 		if len(fields) == 0 {
@@ -1102,11 +1040,11 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 	return nil, err
 }
 
-// Update{{modelName}} updates {{modelName}} by Id and returns error if
+// Update{{.modelName}} updates {{.modelName}} by Id and returns error if
 // the record to be updated doesn't exist
-func Update{{modelName}}ById(m *{{modelName}}) (err error) {
+func Update{{.modelName}}ById(m *{{.modelName}}) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: m.Id}
+	v := {{.modelName}}{Id: m.Id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
@@ -1117,25 +1055,25 @@ func Update{{modelName}}ById(m *{{modelName}}) (err error) {
 	return
 }
 
-// Delete{{modelName}} deletes {{modelName}} by Id and returns error if
+// Delete{{.modelName}} deletes {{.modelName}} by Id and returns error if
 // the record to be deleted doesn't exist
-func Delete{{modelName}}(id int) (err error) {
+func Delete{{.modelName}}(id int) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: id}
+	v := {{.modelName}}{Id: id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
-		if num, err = o.Delete(&{{modelName}}{Id: id}); err == nil {
+		if num, err = o.Delete(&{{.modelName}}{Id: id}); err == nil {
 			fmt.Println("Number of records deleted in database:", num)
 		}
 	}
 	return
 }
-`
-	CtrlTPL = `package controllers
+`)
+	CtrlTPL = NewTemplate("appcode", "controller", appcodeTemplateDoc, `package controllers
 
 import (
-	"{{pkgPath}}/models"
+	"{{.pkgPath}}/models"
 	"encoding/json"
 	"errors"
 	"strconv"
@@ -1144,13 +1082,13 @@ import (
 	"github.com/cisordeng/beego"
 )
 
-// {{ctrlName}}Controller operations for {{ctrlName}}
-type {{ctrlName}}Controller struct {
+// {{.ctrlName}}Controller operations for {{.ctrlName}}
+type {{.ctrlName}}Controller struct {
 	beego.Controller
 }
 
 // URLMapping ...
-func (c *{{ctrlName}}Controller) URLMapping() {
+func (c *{{.ctrlName}}Controller) URLMapping() {
 	c.Mapping("Post", c.Post)
 	c.Mapping("GetOne", c.GetOne)
 	c.Mapping("GetAll", c.GetAll)
@@ -1160,15 +1098,15 @@ func (c *{{ctrlName}}Controller) URLMapping() {
 
 // Post ...
 // @Title Post
-// @Description create {{ctrlName}}
-// @Param	body		body 	models.{{ctrlName}}	true		"body for {{ctrlName}} content"
-// @Success 201 {int} models.{{ctrlName}}
+// @Description create {{.ctrlName}}
+// @Param	body		body 	models.{{.ctrlName}}	true		"body for {{.ctrlName}} content"
+// @Success 201 {int} models.{{.ctrlName}}
 // @Failure 403 body is empty
 // @router / [post]
-func (c *{{ctrlName}}Controller) Post() {
-	var v models.{{ctrlName}}
+func (c *{{.ctrlName}}Controller) Post() {
+	var v models.{{.ctrlName}}
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
-		if _, err := models.Add{{ctrlName}}(&v); err == nil {
+		if _, err := models.Add{{.ctrlName}}(&v); err == nil {
 			c.Ctx.Output.SetStatus(201)
 			c.Data["json"] = v
 		} else {
@@ -1182,15 +1120,15 @@ func (c *{{ctrlName}}Controller) Post() {
 
 // GetOne ...
 // @Title Get One
-// @Description get {{ctrlName}} by id
+// @Description get {{.ctrlName}} by id
 // @Param	id		path 	string	true		"The key for staticblock"
-// @Success 200 {object} models.{{ctrlName}}
+// @Success 200 {object} models.{{.ctrlName}}
 // @Failure 403 :id is empty
 // @router /:id [get]
-func (c *{{ctrlName}}Controller) GetOne() {
+func (c *{{.ctrlName}}Controller) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
-	v, err := models.Get{{ctrlName}}ById(id)
+	v, err := models.Get{{.ctrlName}}ById(id)
 	if err != nil {
 		c.Data["json"] = err.Error()
 	} else {
@@ -1201,17 +1139,17 @@ func (c *{{ctrlName}}Controller) GetOne() {
 
 // GetAll ...
 // @Title Get All
-// @Description get {{ctrlName}}
+// @Description get {{.ctrlName}}
 // @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
 // @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.{{ctrlName}}
+// @Success 200 {object} models.{{.ctrlName}}
 // @Failure 403
 // @router / [get]
-func (c *{{ctrlName}}Controller) GetAll() {
+func (c *{{.ctrlName}}Controller) GetAll() {
 	var fields []string
 	var sortby []string
 	var order []string
@@ -1253,7 +1191,7 @@ func (c *{{ctrlName}}Controller) GetAll() {
 		}
 	}
 
-	l, err := models.GetAll{{ctrlName}}(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAll{{.ctrlName}}(query, fields, sortby, order, offset, limit)
 	if err != nil {
 		c.Data["json"] = err.Error()
 	} else {
@@ -1264,18 +1202,18 @@ func (c *{{ctrlName}}Controller) GetAll() {
 
 // Put ...
 // @Title Put
-// @Description update the {{ctrlName}}
+// @Description update the {{.ctrlName}}
 // @Param	id		path 	string	true		"The id you want to update"
-// @Param	body		body 	models.{{ctrlName}}	true		"body for {{ctrlName}} content"
-// @Success 200 {object} models.{{ctrlName}}
+// @Param	body		body 	models.{{.ctrlName}}	true		"body for {{.ctrlName}} content"
+// @Success 200 {object} models.{{.ctrlName}}
 // @Failure 403 :id is not int
 // @router /:id [put]
-func (c *{{ctrlName}}Controller) Put() {
+func (c *{{.ctrlName}}Controller) Put() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
-	v := models.{{ctrlName}}{Id: id}
+	v := models.{{.ctrlName}}{Id: id}
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
-		if err := models.Update{{ctrlName}}ById(&v); err == nil {
+		if err := models.Update{{.ctrlName}}ById(&v); err == nil {
 			c.Data["json"] = "OK"
 		} else {
 			c.Data["json"] = err.Error()
@@ -1288,23 +1226,23 @@ func (c *{{ctrlName}}Controller) Put() {
 
 // Delete ...
 // @Title Delete
-// @Description delete the {{ctrlName}}
+// @Description delete the {{.ctrlName}}
 // @Param	id		path 	string	true		"The id you want to delete"
 // @Success 200 {string} delete success!
 // @Failure 403 id is empty
 // @router /:id [delete]
-func (c *{{ctrlName}}Controller) Delete() {
+func (c *{{.ctrlName}}Controller) Delete() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
-	if err := models.Delete{{ctrlName}}(id); err == nil {
+	if err := models.Delete{{.ctrlName}}(id); err == nil {
 		c.Data["json"] = "OK"
 	} else {
 		c.Data["json"] = err.Error()
 	}
 	c.ServeJSON()
 }
-`
-	RouterTPL = `// @APIVersion 1.0.0
+`)
+	RouterTPL = NewTemplate("appcode", "router", appcodeTemplateDoc, `// @APIVersion 1.0.0
 // @Title beego Test API
 // @Description beego has a very cool tools to autogenerate documents for your API
 // @Contact cisordeng@gmail.com
@@ -1314,23 +1252,22 @@ func (c *{{ctrlName}}Controller) Delete() {
 package routers
 
 import (
-	"{{pkgPath}}/controllers"
+	"{{.pkgPath}}/controllers"
 
 	"github.com/cisordeng/beego"
 )
 
 func init() {
 	ns := beego.NewNamespace("/v1",
-		{{nameSpaces}}
-	)
-	beego.AddNamespace(ns)
-}
-`
-	NamespaceTPL = `
-		beego.NSNamespace("/{{nameSpace}}",
+{{- range .nameSpaces}}
+		beego.NSNamespace("/{{.nameSpace}}",
 			beego.NSInclude(
-				&controllers.{{ctrlName}}Controller{},
+				&controllers.{{.ctrlName}}Controller{},
 			),
 		),
-`
+{{- end}}
+	)
+	beego.AddNamespace(ns)
+}
+`)
 )
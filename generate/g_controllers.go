@@ -15,19 +15,14 @@
 package generate
 
 import (
-	"fmt"
 	"os"
 	"path"
 	"strings"
 
 	beeLogger "github.com/cisordeng/bee/logger"
-	"github.com/cisordeng/bee/logger/colors"
-	"github.com/cisordeng/bee/utils"
 )
 
 func GenerateController(cname, currpath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
 	p, f := path.Split(cname)
 	controllerName := strings.Title(f)
 	packageName := "controllers"
@@ -40,54 +35,49 @@ func GenerateController(cname, currpath string) {
 	beeLogger.Log.Infof("Using '%s' as controller name", controllerName)
 	beeLogger.Log.Infof("Using '%s' as package name", packageName)
 
-	fp := path.Join(currpath, "controllers", p)
-	if _, err := os.Stat(fp); os.IsNotExist(err) {
-		// Create the controller's directory
-		if err := os.MkdirAll(fp, 0777); err != nil {
-			beeLogger.Log.Fatalf("Could not create controllers directory: %s", err)
-		}
-	}
-
-	fpath := path.Join(fp, strings.ToLower(controllerName)+".go")
-	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-
-		modelPath := path.Join(currpath, "models", strings.ToLower(controllerName)+".go")
-
-		var content string
-		if _, err := os.Stat(modelPath); err == nil {
-			beeLogger.Log.Infof("Using matching model '%s'", controllerName)
-			content = strings.Replace(controllerModelTpl, "{{packageName}}", packageName, -1)
-			pkgPath := getPackagePath(currpath)
-			content = strings.Replace(content, "{{pkgPath}}", pkgPath, -1)
-		} else {
-			content = strings.Replace(controllerTpl, "{{packageName}}", packageName, -1)
-		}
-
-		content = strings.Replace(content, "{{controllerName}}", controllerName, -1)
-		f.WriteString(content)
+	fpath := path.Join(currpath, "controllers", p, strings.ToLower(controllerName)+".go")
+	modelPath := path.Join(currpath, "models", strings.ToLower(controllerName)+".go")
 
-		// Run 'gofmt' on the generated source code
-		utils.FormatSourceCode(fpath)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
+	data := map[string]string{
+		"packageName":    packageName,
+		"controllerName": controllerName,
+	}
+	var content string
+	if _, err := os.Stat(modelPath); err == nil {
+		beeLogger.Log.Infof("Using matching model '%s'", controllerName)
+		data["pkgPath"] = getPackagePath(currpath)
+		content = controllerModelTpl.Render(currpath, data)
 	} else {
-		beeLogger.Log.Fatalf("Could not create controller file: %s", err)
+		content = controllerTpl.Render(currpath, data)
 	}
+	plan := &Plan{}
+	plan.Add(fpath, content)
+	plan.Apply()
 }
 
-var controllerTpl = `package {{packageName}}
+// controllerTemplateDoc describes the data of the controller templates
+const controllerTemplateDoc = `
+Data of the templates of "bee generate controller", e.g. for admin/user:
+
+  .packageName     Package of the controller, e.g. admin
+  .controllerName  Name of the controller, e.g. User
+  .pkgPath         Import path of the application, only for controller_model,
+                   which is used when the model models/<name>.go exists
+`
+
+var controllerTpl = NewTemplate("controller", "controller", controllerTemplateDoc, `package {{.packageName}}
 
 import (
 	"github.com/cisordeng/beego"
 )
 
-// {{controllerName}}Controller operations for {{controllerName}}
-type {{controllerName}}Controller struct {
+// {{.controllerName}}Controller operations for {{.controllerName}}
+type {{.controllerName}}Controller struct {
 	beego.Controller
 }
 
 // URLMapping ...
-func (c *{{controllerName}}Controller) URLMapping() {
+func (c *{{.controllerName}}Controller) URLMapping() {
 	c.Mapping("Post", c.Post)
 	c.Mapping("GetOne", c.GetOne)
 	c.Mapping("GetAll", c.GetAll)
@@ -97,70 +87,70 @@ func (c *{{controllerName}}Controller) URLMapping() {
 
 // Post ...
 // @Title Create
-// @Description create {{controllerName}}
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 201 {object} models.{{controllerName}}
+// @Description create {{.controllerName}}
+// @Param	body		body 	models.{{.controllerName}}	true		"body for {{.controllerName}} content"
+// @Success 201 {object} models.{{.controllerName}}
 // @Failure 403 body is empty
 // @router / [post]
-func (c *{{controllerName}}Controller) Post() {
+func (c *{{.controllerName}}Controller) Post() {
 
 }
 
 // GetOne ...
 // @Title GetOne
-// @Description get {{controllerName}} by id
+// @Description get {{.controllerName}} by id
 // @Param	id		path 	string	true		"The key for staticblock"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.controllerName}}
 // @Failure 403 :id is empty
 // @router /:id [get]
-func (c *{{controllerName}}Controller) GetOne() {
+func (c *{{.controllerName}}Controller) GetOne() {
 
 }
 
 // GetAll ...
 // @Title GetAll
-// @Description get {{controllerName}}
+// @Description get {{.controllerName}}
 // @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
 // @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.controllerName}}
 // @Failure 403
 // @router / [get]
-func (c *{{controllerName}}Controller) GetAll() {
+func (c *{{.controllerName}}Controller) GetAll() {
 
 }
 
 // Put ...
 // @Title Put
-// @Description update the {{controllerName}}
+// @Description update the {{.controllerName}}
 // @Param	id		path 	string	true		"The id you want to update"
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 200 {object} models.{{controllerName}}
+// @Param	body		body 	models.{{.controllerName}}	true		"body for {{.controllerName}} content"
+// @Success 200 {object} models.{{.controllerName}}
 // @Failure 403 :id is not int
 // @router /:id [put]
-func (c *{{controllerName}}Controller) Put() {
+func (c *{{.controllerName}}Controller) Put() {
 
 }
 
 // Delete ...
 // @Title Delete
-// @Description delete the {{controllerName}}
+// @Description delete the {{.controllerName}}
 // @Param	id		path 	string	true		"The id you want to delete"
 // @Success 200 {string} delete success!
 // @Failure 403 id is empty
 // @router /:id [delete]
-func (c *{{controllerName}}Controller) Delete() {
+func (c *{{.controllerName}}Controller) Delete() {
 
 }
-`
+`)
 
-var controllerModelTpl = `package {{packageName}}
+var controllerModelTpl = NewTemplate("controller", "controller_model", controllerTemplateDoc, `package {{.packageName}}
 
 import (
-	"{{pkgPath}}/models"
+	"{{.pkgPath}}/models"
 	"encoding/json"
 	"errors"
 	"strconv"
@@ -169,13 +159,13 @@ import (
 	"github.com/cisordeng/beego"
 )
 
-//  {{controllerName}}Controller operations for {{controllerName}}
-type {{controllerName}}Controller struct {
+//  {{.controllerName}}Controller operations for {{.controllerName}}
+type {{.controllerName}}Controller struct {
 	beego.Controller
 }
 
 // URLMapping ...
-func (c *{{controllerName}}Controller) URLMapping() {
+func (c *{{.controllerName}}Controller) URLMapping() {
 	c.Mapping("Post", c.Post)
 	c.Mapping("GetOne", c.GetOne)
 	c.Mapping("GetAll", c.GetAll)
@@ -185,15 +175,15 @@ func (c *{{controllerName}}Controller) URLMapping() {
 
 // Post ...
 // @Title Post
-// @Description create {{controllerName}}
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 201 {int} models.{{controllerName}}
+// @Description create {{.controllerName}}
+// @Param	body		body 	models.{{.controllerName}}	true		"body for {{.controllerName}} content"
+// @Success 201 {int} models.{{.controllerName}}
 // @Failure 403 body is empty
 // @router / [post]
-func (c *{{controllerName}}Controller) Post() {
-	var v models.{{controllerName}}
+func (c *{{.controllerName}}Controller) Post() {
+	var v models.{{.controllerName}}
 	json.Unmarshal(c.Ctx.Input.RequestBody, &v)
-	if _, err := models.Add{{controllerName}}(&v); err == nil {
+	if _, err := models.Add{{.controllerName}}(&v); err == nil {
 		c.Ctx.Output.SetStatus(201)
 		c.Data["json"] = v
 	} else {
@@ -204,15 +194,15 @@ func (c *{{controllerName}}Controller) Post() {
 
 // GetOne ...
 // @Title Get One
-// @Description get {{controllerName}} by id
+// @Description get {{.controllerName}} by id
 // @Param	id		path 	string	true		"The key for staticblock"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.controllerName}}
 // @Failure 403 :id is empty
 // @router /:id [get]
-func (c *{{controllerName}}Controller) GetOne() {
+func (c *{{.controllerName}}Controller) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.ParseInt(idStr, 0, 64)
-	v, err := models.Get{{controllerName}}ById(id)
+	v, err := models.Get{{.controllerName}}ById(id)
 	if err != nil {
 		c.Data["json"] = err.Error()
 	} else {
@@ -223,17 +213,17 @@ func (c *{{controllerName}}Controller) GetOne() {
 
 // GetAll ...
 // @Title Get All
-// @Description get {{controllerName}}
+// @Description get {{.controllerName}}
 // @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
 // @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.controllerName}}
 // @Failure 403
 // @router / [get]
-func (c *{{controllerName}}Controller) GetAll() {
+func (c *{{.controllerName}}Controller) GetAll() {
 	var fields []string
 	var sortby []string
 	var order []string
@@ -275,7 +265,7 @@ func (c *{{controllerName}}Controller) GetAll() {
 		}
 	}
 
-	l, err := models.GetAll{{controllerName}}(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAll{{.controllerName}}(query, fields, sortby, order, offset, limit)
 	if err != nil {
 		c.Data["json"] = err.Error()
 	} else {
@@ -286,18 +276,18 @@ func (c *{{controllerName}}Controller) GetAll() {
 
 // Put ...
 // @Title Put
-// @Description update the {{controllerName}}
+// @Description update the {{.controllerName}}
 // @Param	id		path 	string	true		"The id you want to update"
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 200 {object} models.{{controllerName}}
+// @Param	body		body 	models.{{.controllerName}}	true		"body for {{.controllerName}} content"
+// @Success 200 {object} models.{{.controllerName}}
 // @Failure 403 :id is not int
 // @router /:id [put]
-func (c *{{controllerName}}Controller) Put() {
+func (c *{{.controllerName}}Controller) Put() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.ParseInt(idStr, 0, 64)
-	v := models.{{controllerName}}{Id: id}
+	v := models.{{.controllerName}}{Id: id}
 	json.Unmarshal(c.Ctx.Input.RequestBody, &v)
-	if err := models.Update{{controllerName}}ById(&v); err == nil {
+	if err := models.Update{{.controllerName}}ById(&v); err == nil {
 		c.Data["json"] = "OK"
 	} else {
 		c.Data["json"] = err.Error()
@@ -307,19 +297,19 @@ func (c *{{controllerName}}Controller) Put() {
 
 // Delete ...
 // @Title Delete
-// @Description delete the {{controllerName}}
+// @Description delete the {{.controllerName}}
 // @Param	id		path 	string	true		"The id you want to delete"
 // @Success 200 {string} delete success!
 // @Failure 403 id is empty
 // @router /:id [delete]
-func (c *{{controllerName}}Controller) Delete() {
+func (c *{{.controllerName}}Controller) Delete() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.ParseInt(idStr, 0, 64)
-	if err := models.Delete{{controllerName}}(id); err == nil {
+	if err := models.Delete{{.controllerName}}(id); err == nil {
 		c.Data["json"] = "OK"
 	} else {
 		c.Data["json"] = err.Error()
 	}
 	c.ServeJSON()
 }
-`
+`)
@@ -19,32 +19,49 @@ package generate
 
 import (
 	"database/sql"
-	"fmt"
-	"os"
 	"path"
 	"strings"
 
 	beeLogger "github.com/cisordeng/bee/logger"
-	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 )
 
-var Hproseconf = `appname = {{.Appname}}
+// hproseTemplateDoc describes the data of the hprose templates
+const hproseTemplateDoc = `
+Data of the templates of "bee hprose", e.g. for bee hprose hello:
+
+  .Appname      Name of the application, e.g. hello (conf)
+  .PkgPath      Import path of the application (main, main_conn)
+  .DriverName   Database driver, mysql or postgres, with -conn (main_conn)
+  .DriverPkg    Import of the database driver, with -conn (main_conn)
+  .conn         Connection string of the database, with -conn (main_conn)
+  .Models       Names of the models whose functions are published, with -conn (main_conn)
+  .modelStruct  Struct of the model of a table, with -conn (struct_model, model)
+  .modelName    Name of the model of a table, e.g. UserAccount (struct_model, model)
+  .importTime   Whether the model has time fields (struct_model, model)
+
+main and the object and user models are used without -conn, main_conn and
+the models of the tables otherwise. struct_model is used for the tables
+without primary key, model for the others.
+`
+
+var Hproseconf = NewTemplate("hprose", "conf", hproseTemplateDoc, `appname = {{.Appname}}
 httpport = 8080
 runmode = dev
 autorender = false
 copyrequestbody = true
 EnableDocs = true
-`
-var HproseMaingo = `package main
+`)
+
+var HproseMaingo = NewTemplate("hprose", "main", hproseTemplateDoc, `package main
 
 import (
 	"fmt"
 	"reflect"
 
-	"{{.Appname}}/models"
+	"{{.PkgPath}}/models"
 	"github.com/hprose/hprose-golang/rpc"
 
 	"github.com/cisordeng/beego"
@@ -79,15 +96,15 @@ func main() {
 	beego.Handler("/", service)
 	beego.Run()
 }
-`
+`)
 
-var HproseMainconngo = `package main
+var HproseMainconngo = NewTemplate("hprose", "main_conn", hproseTemplateDoc, `package main
 
 import (
 	"fmt"
 	"reflect"
 
-	"{{.Appname}}/models"
+	"{{.PkgPath}}/models"
 	"github.com/hprose/hprose-golang/rpc"
 
 	"github.com/cisordeng/beego"
@@ -120,16 +137,24 @@ func main() {
 	// Use Logger Middleware
 	service.AddInvokeHandler(logInvokeHandler)
 
-	{{HproseFunctionList}}
+{{- range .Models}}
+
+	// publish about {{.}} function
+	service.AddFunction("Add{{.}}", models.Add{{.}})
+	service.AddFunction("Get{{.}}ById", models.Get{{.}}ById)
+	service.AddFunction("GetAll{{.}}", models.GetAll{{.}})
+	service.AddFunction("Update{{.}}ById", models.Update{{.}}ById)
+	service.AddFunction("Delete{{.}}", models.Delete{{.}})
+{{- end}}
 
 	// Start Service
 	beego.Handler("/", service)
 	beego.Run()
 }
 
-`
+`)
 
-var HproseModels = `package models
+var HproseModels = NewTemplate("hprose", "object_model", hproseTemplateDoc, `package models
 
 import (
 	"errors"
@@ -182,9 +207,9 @@ func Delete(ObjectId string) {
 	delete(Objects, ObjectId)
 }
 
-`
+`)
 
-var HproseModels2 = `package models
+var HproseModels2 = NewTemplate("hprose", "user_model", hproseTemplateDoc, `package models
 
 import (
 	"errors"
@@ -270,8 +295,11 @@ func Login(username, password string) bool {
 func DeleteUser(uid string) {
 	delete(UserList, uid)
 }
-`
-var HproseAddFunctions = []string{}
+`)
+
+// HproseModelNames holds the names of the models generated with a primary key,
+// whose functions are published by the main_conn template
+var HproseModelNames []string
 
 func GenerateHproseAppcode(driver, connStr, level, tables, currpath string) {
 	var mode byte
@@ -317,28 +345,26 @@ func genHprose(dbms, connStr string, mode byte, selectedTableNames map[string]bo
 		tables := getTableObjects(tableNames, db, trans)
 		mvcPath := new(MvcPath)
 		mvcPath.ModelPath = path.Join(currpath, "models")
-		createPaths(mode, mvcPath)
 		pkgPath := getPackagePath(currpath)
-		writeHproseSourceFiles(pkgPath, tables, mode, mvcPath, selectedTableNames)
+		writeHproseSourceFiles(currpath, pkgPath, tables, mode, mvcPath, selectedTableNames)
 	} else {
 		beeLogger.Log.Fatalf("Generating app code from '%s' database is not supported yet", dbms)
 	}
 }
 
 // writeHproseSourceFiles generates source files for model/controller/router
-// It will wipe the following directories and recreate them:./models, ./controllers, ./routers
 // Newly geneated files will be inside these folders.
-func writeHproseSourceFiles(pkgPath string, tables []*Table, mode byte, paths *MvcPath, selectedTables map[string]bool) {
+func writeHproseSourceFiles(apppath, pkgPath string, tables []*Table, mode byte, paths *MvcPath, selectedTables map[string]bool) {
+	plan := &Plan{}
 	if (OModel & mode) == OModel {
 		beeLogger.Log.Info("Creating model files...")
-		writeHproseModelFiles(tables, paths.ModelPath, selectedTables)
+		addHproseModelFiles(plan, apppath, tables, paths.ModelPath, selectedTables)
 	}
+	plan.Apply()
 }
 
-// writeHproseModelFiles generates model files
-func writeHproseModelFiles(tables []*Table, mPath string, selectedTables map[string]bool) {
-	w := colors.NewColorWriter(os.Stdout)
-
+// addHproseModelFiles plans the model files
+func addHproseModelFiles(plan *Plan, apppath string, tables []*Table, mPath string, selectedTables map[string]bool) {
 	for _, tb := range tables {
 		// if selectedTables map is not nil and this table is not selected, ignore it
 		if selectedTables != nil {
@@ -348,111 +374,74 @@ func writeHproseModelFiles(tables []*Table, mPath string, selectedTables map[str
 		}
 		filename := getFileName(tb.Name)
 		fpath := path.Join(mPath, filename+".go")
-		var f *os.File
-		var err error
-		if utils.IsExist(fpath) {
-			beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-			if utils.AskForConfirmation() {
-				f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-				if err != nil {
-					beeLogger.Log.Warnf("%s", err)
-					continue
-				}
-			} else {
-				beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-				continue
-			}
-		} else {
-			f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				continue
-			}
-		}
-		var template string
+		modelName := utils.CamelCase(tb.Name)
+		template := HproseModelTPL
 		if tb.Pk == "" {
 			template = HproseStructModelTPL
 		} else {
-			template = HproseModelTPL
-			HproseAddFunctions = append(HproseAddFunctions, strings.Replace(HproseAddFunction, "{{modelName}}", utils.CamelCase(tb.Name), -1))
-		}
-		fileStr := strings.Replace(template, "{{modelStruct}}", tb.String(), 1)
-		fileStr = strings.Replace(fileStr, "{{modelName}}", utils.CamelCase(tb.Name), -1)
-		// if table contains time field, import time.Time package
-		timePkg := ""
-		importTimePkg := ""
-		if tb.ImportTimePkg {
-			timePkg = "\"time\"\n"
-			importTimePkg = "import \"time\"\n"
-		}
-		fileStr = strings.Replace(fileStr, "{{timePkg}}", timePkg, -1)
-		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Fatalf("Could not write model file to '%s'", fpath)
+			HproseModelNames = append(HproseModelNames, modelName)
 		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		plan.Add(fpath, template.Render(apppath, map[string]interface{}{
+			"modelStruct": tb.String(),
+			"modelName":   modelName,
+			"importTime":  tb.ImportTimePkg,
+		}))
 	}
 }
 
-const (
-	HproseAddFunction = `
-	// publish about {{modelName}} function
-	service.AddFunction("Add{{modelName}}", models.Add{{modelName}})
-	service.AddFunction("Get{{modelName}}ById", models.Get{{modelName}}ById)
-	service.AddFunction("GetAll{{modelName}}", models.GetAll{{modelName}})
-	service.AddFunction("Update{{modelName}}ById", models.Update{{modelName}}ById)
-	service.AddFunction("Delete{{modelName}}", models.Delete{{modelName}})
-
-`
-	HproseStructModelTPL = `package models
-{{importTimePkg}}
-{{modelStruct}}
-`
+var (
+	HproseStructModelTPL = NewTemplate("hprose", "struct_model", hproseTemplateDoc, `package models
+{{if .importTime}}
+import "time"
+{{end}}
+{{.modelStruct}}
+`)
 
-	HproseModelTPL = `package models
+	HproseModelTPL = NewTemplate("hprose", "model", hproseTemplateDoc, `package models
 
 import (
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
-	{{timePkg}}
+{{- if .importTime}}
+	"time"
+{{- end}}
+
 	"github.com/cisordeng/beego/orm"
 )
 
-{{modelStruct}}
+{{.modelStruct}}
 
 func init() {
-	orm.RegisterModel(new({{modelName}}))
+	orm.RegisterModel(new({{.modelName}}))
 }
 
-// Add{{modelName}} insert a new {{modelName}} into database and returns
+// Add{{.modelName}} insert a new {{.modelName}} into database and returns
 // last inserted Id on success.
-func Add{{modelName}}(m *{{modelName}}) (id int64, err error) {
+func Add{{.modelName}}(m *{{.modelName}}) (id int64, err error) {
 	o := orm.NewOrm()
 	id, err = o.Insert(m)
 	return
 }
 
-// Get{{modelName}}ById retrieves {{modelName}} by Id. Returns error if
+// Get{{.modelName}}ById retrieves {{.modelName}} by Id. Returns error if
 // Id doesn't exist
-func Get{{modelName}}ById(id int) (v *{{modelName}}, err error) {
+func Get{{.modelName}}ById(id int) (v *{{.modelName}}, err error) {
 	o := orm.NewOrm()
-	v = &{{modelName}}{Id: id}
+	v = &{{.modelName}}{Id: id}
 	if err = o.Read(v); err == nil {
 		return v, nil
 	}
 	return nil, err
 }
 
-// GetAll{{modelName}} retrieves all {{modelName}} matches certain condition. Returns empty list if
+// GetAll{{.modelName}} retrieves all {{.modelName}} matches certain condition. Returns empty list if
 // no records exist
-func GetAll{{modelName}}(query map[string]string, fields []string, sortby []string, order []string,
+func GetAll{{.modelName}}(query map[string]string, fields []string, sortby []string, order []string,
 	offset int64, limit int64) (ml []interface{}, err error) {
 	o := orm.NewOrm()
-	qs := o.QueryTable(new({{modelName}}))
+	qs := o.QueryTable(new({{.modelName}}))
 	// query k=v
 	for k, v := range query {
 		// rewrite dot-notation to Object__Attribute
@@ -498,7 +487,7 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 		}
 	}
 
-	var l []{{modelName}}
+	var l []{{.modelName}}
 	qs = qs.OrderBy(sortFields...)
 	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
 		if len(fields) == 0 {
@@ -521,11 +510,11 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 	return nil, err
 }
 
-// Update{{modelName}} updates {{modelName}} by Id and returns error if
+// Update{{.modelName}} updates {{.modelName}} by Id and returns error if
 // the record to be updated doesn't exist
-func Update{{modelName}}ById(m *{{modelName}}) (err error) {
+func Update{{.modelName}}ById(m *{{.modelName}}) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: m.Id}
+	v := {{.modelName}}{Id: m.Id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
@@ -536,19 +525,19 @@ func Update{{modelName}}ById(m *{{modelName}}) (err error) {
 	return
 }
 
-// Delete{{modelName}} deletes {{modelName}} by Id and returns error if
+// Delete{{.modelName}} deletes {{.modelName}} by Id and returns error if
 // the record to be deleted doesn't exist
-func Delete{{modelName}}(id int) (err error) {
+func Delete{{.modelName}}(id int) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: id}
+	v := {{.modelName}}{Id: id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
-		if num, err = o.Delete(&{{modelName}}{Id: id}); err == nil {
+		if num, err = o.Delete(&{{.modelName}}{Id: id}); err == nil {
 			fmt.Println("Number of records deleted in database:", num)
 		}
 	}
 	return
 }
-`
+`)
 )
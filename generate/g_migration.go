@@ -16,13 +16,11 @@ package generate
 
 import (
 	"fmt"
-	"os"
 	"path"
 	"strings"
 	"time"
 
 	"github.com/cisordeng/bee/logger"
-	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
 )
 
@@ -190,101 +188,101 @@ func NewDBDriver() DBDriver {
 // The generated file template consists of an up() method for updating schema and
 // a down() method for reverting the update.
 func GenerateMigration(mname, upsql, downsql, curpath string) {
-	w := colors.NewColorWriter(os.Stdout)
-	migrationFilePath := path.Join(curpath, DBPath, MPath)
-	if _, err := os.Stat(migrationFilePath); os.IsNotExist(err) {
-		// create migrations directory
-		if err := os.MkdirAll(migrationFilePath, 0777); err != nil {
-			beeLogger.Log.Fatalf("Could not create migration directory: %s", err)
-		}
-	}
-	// create file
 	today := time.Now().Format(MDateFormat)
-	fpath := path.Join(migrationFilePath, fmt.Sprintf("%s_%s.go", today, mname))
-	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		ddlSpec := ""
-		spec := ""
-		up := ""
-		down := ""
-		if DDL != "" {
-			ddlSpec = "m.ddlSpec()"
-			switch strings.Title(DDL.String()) {
-			case "Create":
-				spec = strings.Replace(DDLSpecCreate, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-			case "Alter":
-				spec = strings.Replace(DDLSpecAlter, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-			}
-			spec = strings.Replace(spec, "{{tableName}}", mname, -1)
-		} else {
-			up = strings.Replace(MigrationUp, "{{UpSQL}}", upsql, -1)
-			up = strings.Replace(up, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-			down = strings.Replace(MigrationDown, "{{DownSQL}}", downsql, -1)
-			down = strings.Replace(down, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
+	fpath := path.Join(curpath, DBPath, MPath, fmt.Sprintf("%s_%s.go", today, mname))
+	data := map[string]string{
+		"StructName": utils.CamelCase(mname) + "_" + today,
+		"CurrTime":   today,
+		"tableName":  mname,
+		"UpSQL":      upsql,
+		"DownSQL":    downsql,
+		"ddlSpec":    "",
+	}
+	spec := ""
+	up := ""
+	down := ""
+	if DDL != "" {
+		data["ddlSpec"] = "m.ddlSpec()"
+		switch strings.Title(DDL.String()) {
+		case "Create":
+			spec = DDLSpecCreate.Render(curpath, data)
+		case "Alter":
+			spec = DDLSpecAlter.Render(curpath, data)
 		}
-
-		header := strings.Replace(MigrationHeader, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-		header = strings.Replace(header, "{{ddlSpec}}", ddlSpec, -1)
-		header = strings.Replace(header, "{{CurrTime}}", today, -1)
-		f.WriteString(header + spec + up + down)
-		// Run 'gofmt' on the generated source code
-		utils.FormatSourceCode(fpath)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
 	} else {
-		beeLogger.Log.Fatalf("Could not create migration file: %s", err)
+		up = MigrationUp.Render(curpath, data)
+		down = MigrationDown.Render(curpath, data)
 	}
+
+	header := MigrationHeader.Render(curpath, data)
+	plan := &Plan{}
+	plan.Add(fpath, header+spec+up+down)
+	plan.Apply()
 }
 
-const (
-	MigrationHeader = `package main
+// migrationTemplateDoc describes the data of the migration templates
+const migrationTemplateDoc = `
+Data of the templates of "bee generate migration". A migration is made of the
+header, followed either by ddl_create or ddl_alter with -ddl, or by up and down.
+
+  .StructName  Name of the migration, e.g. CreateUser_20190512_150405
+  .CurrTime    Time of the creation of the migration, e.g. 20190512_150405
+  .ddlSpec     "m.ddlSpec()" with -ddl
+  .tableName   Name of the table, with -ddl
+  .UpSQL       Statements of the Up migration, given by -fields
+  .DownSQL     Statements of the Down migration, given by -fields
+`
+
+var (
+	MigrationHeader = NewTemplate("migration", "header", migrationTemplateDoc, `package main
 						import (
 							"github.com/cisordeng/beego/migration"
 						)
 
 						// DO NOT MODIFY
-						type {{StructName}} struct {
+						type {{.StructName}} struct {
 							migration.Migration
 						}
 
 						// DO NOT MODIFY
 						func init() {
-							m := &{{StructName}}{}
-							m.Created = "{{CurrTime}}"
-							{{ddlSpec}}
-							migration.Register("{{StructName}}", m)
+							m := &{{.StructName}}{}
+							m.Created = "{{.CurrTime}}"
+							{{.ddlSpec}}
+							migration.Register("{{.StructName}}", m)
 						}
-					   `
+					   `)
 
-	DDLSpecCreate = `
+	DDLSpecCreate = NewTemplate("migration", "ddl_create", migrationTemplateDoc, `
 				/*
 				refer beego/migration/doc.go
 				*/
-				func(m *{{StructName}}) ddlSpec(){
-				m.CreateTable("{{tableName}}", "InnoDB", "utf8")
+				func(m *{{.StructName}}) ddlSpec(){
+				m.CreateTable("{{.tableName}}", "InnoDB", "utf8")
 				m.PriCol("id").SetAuto(true).SetNullable(false).SetDataType("INT(10)").SetUnsigned(true)
 
 				}
-				`
-	DDLSpecAlter = `
+				`)
+	DDLSpecAlter = NewTemplate("migration", "ddl_alter", migrationTemplateDoc, `
 				/*
 				refer beego/migration/doc.go
 				*/
-				func(m *{{StructName}}) ddlSpec(){
-				m.AlterTable("{{tableName}}")
+				func(m *{{.StructName}}) ddlSpec(){
+				m.AlterTable("{{.tableName}}")
 
 				}
-				`
-	MigrationUp = `
+				`)
+	MigrationUp = NewTemplate("migration", "up", migrationTemplateDoc, `
 				// Run the migrations
-				func (m *{{StructName}}) Up() {
+				func (m *{{.StructName}}) Up() {
 					// use m.SQL("CREATE TABLE ...") to make schema update
-					{{UpSQL}}
-				}`
-	MigrationDown = `
+					{{.UpSQL}}
+				}`)
+	MigrationDown = NewTemplate("migration", "down", migrationTemplateDoc, `
 				// Reverse the migrations
-				func (m *{{StructName}}) Down() {
+				func (m *{{.StructName}}) Down() {
 					// use m.SQL("DROP TABLE ...") to reverse schema update
-					{{DownSQL}}
+					{{.DownSQL}}
 				}
-				`
+				`)
 )
@@ -16,19 +16,14 @@ package generate
 
 import (
 	"errors"
-	"fmt"
-	"os"
 	"path"
 	"strings"
 
 	beeLogger "github.com/cisordeng/bee/logger"
-	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
 )
 
 func GenerateModel(mname, fields, currpath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
 	p, f := path.Split(mname)
 	modelName := strings.Title(f)
 	packageName := "models"
@@ -45,32 +40,19 @@ func GenerateModel(mname, fields, currpath string) {
 	beeLogger.Log.Infof("Using '%s' as model name", modelName)
 	beeLogger.Log.Infof("Using '%s' as package name", packageName)
 
-	fp := path.Join(currpath, "models", p)
-	if _, err := os.Stat(fp); os.IsNotExist(err) {
-		// Create the model's directory
-		if err := os.MkdirAll(fp, 0777); err != nil {
-			beeLogger.Log.Fatalf("Could not create the model directory: %s", err)
-		}
-	}
-
-	fpath := path.Join(fp, strings.ToLower(modelName)+".go")
-	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		content := strings.Replace(modelTpl, "{{packageName}}", packageName, -1)
-		content = strings.Replace(content, "{{modelName}}", modelName, -1)
-		content = strings.Replace(content, "{{modelStruct}}", modelStruct, -1)
-		if hastime {
-			content = strings.Replace(content, "{{timePkg}}", `"time"`, -1)
-		} else {
-			content = strings.Replace(content, "{{timePkg}}", "", -1)
-		}
-		f.WriteString(content)
-		// Run 'gofmt' on the generated source code
-		utils.FormatSourceCode(fpath)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create model file: %s", err)
+	fpath := path.Join(currpath, "models", p, strings.ToLower(modelName)+".go")
+	timePkg := ""
+	if hastime {
+		timePkg = `"time"`
 	}
+	plan := &Plan{}
+	plan.Add(fpath, modelTpl.Render(currpath, map[string]string{
+		"packageName": packageName,
+		"modelName":   modelName,
+		"modelStruct": modelStruct,
+		"timePkg":     timePkg,
+	}))
+	plan.Apply()
 }
 
 func getStruct(structname, fields string) (string, bool, error) {
@@ -137,48 +119,58 @@ func getType(ktype string) (kt, tag string, hasTime bool) {
 	return "", "", false
 }
 
-var modelTpl = `package {{packageName}}
+// modelTemplateDoc describes the data of the model template
+const modelTemplateDoc = `
+Data of the template of "bee generate model", e.g. for user -fields="name:string":
+
+  .packageName  Package of the model, e.g. models
+  .modelName    Name of the model, e.g. User
+  .modelStruct  Source code of the struct of the model
+  .timePkg      "time" if a field is a time.Time, to be imported
+`
+
+var modelTpl = NewTemplate("model", "model", modelTemplateDoc, `package {{.packageName}}
 
 import (
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
-	{{timePkg}}
+	{{.timePkg}}
 	"github.com/cisordeng/beego/orm"
 )
 
-{{modelStruct}}
+{{.modelStruct}}
 
 func init() {
-	orm.RegisterModel(new({{modelName}}))
+	orm.RegisterModel(new({{.modelName}}))
 }
 
-// Add{{modelName}} insert a new {{modelName}} into database and returns
+// Add{{.modelName}} insert a new {{.modelName}} into database and returns
 // last inserted Id on success.
-func Add{{modelName}}(m *{{modelName}}) (id int64, err error) {
+func Add{{.modelName}}(m *{{.modelName}}) (id int64, err error) {
 	o := orm.NewOrm()
 	id, err = o.Insert(m)
 	return
 }
 
-// Get{{modelName}}ById retrieves {{modelName}} by Id. Returns error if
+// Get{{.modelName}}ById retrieves {{.modelName}} by Id. Returns error if
 // Id doesn't exist
-func Get{{modelName}}ById(id int64) (v *{{modelName}}, err error) {
+func Get{{.modelName}}ById(id int64) (v *{{.modelName}}, err error) {
 	o := orm.NewOrm()
-	v = &{{modelName}}{Id: id}
-	if err = o.QueryTable(new({{modelName}})).Filter("Id", id).RelatedSel().One(v); err == nil {
+	v = &{{.modelName}}{Id: id}
+	if err = o.QueryTable(new({{.modelName}})).Filter("Id", id).RelatedSel().One(v); err == nil {
 		return v, nil
 	}
 	return nil, err
 }
 
-// GetAll{{modelName}} retrieves all {{modelName}} matches certain condition. Returns empty list if
+// GetAll{{.modelName}} retrieves all {{.modelName}} matches certain condition. Returns empty list if
 // no records exist
-func GetAll{{modelName}}(query map[string]string, fields []string, sortby []string, order []string,
+func GetAll{{.modelName}}(query map[string]string, fields []string, sortby []string, order []string,
 	offset int64, limit int64) (ml []interface{}, err error) {
 	o := orm.NewOrm()
-	qs := o.QueryTable(new({{modelName}}))
+	qs := o.QueryTable(new({{.modelName}}))
 	// query k=v
 	for k, v := range query {
 		// rewrite dot-notation to Object__Attribute
@@ -224,7 +216,7 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 		}
 	}
 
-	var l []{{modelName}}
+	var l []{{.modelName}}
 	qs = qs.OrderBy(sortFields...).RelatedSel()
 	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
 		if len(fields) == 0 {
@@ -247,11 +239,11 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 	return nil, err
 }
 
-// Update{{modelName}} updates {{modelName}} by Id and returns error if
+// Update{{.modelName}} updates {{.modelName}} by Id and returns error if
 // the record to be updated doesn't exist
-func Update{{modelName}}ById(m *{{modelName}}) (err error) {
+func Update{{.modelName}}ById(m *{{.modelName}}) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: m.Id}
+	v := {{.modelName}}{Id: m.Id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
@@ -262,18 +254,18 @@ func Update{{modelName}}ById(m *{{modelName}}) (err error) {
 	return
 }
 
-// Delete{{modelName}} deletes {{modelName}} by Id and returns error if
+// Delete{{.modelName}} deletes {{.modelName}} by Id and returns error if
 // the record to be deleted doesn't exist
-func Delete{{modelName}}(id int64) (err error) {
+func Delete{{.modelName}}(id int64) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: id}
+	v := {{.modelName}}{Id: id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
-		if num, err = o.Delete(&{{modelName}}{Id: id}); err == nil {
+		if num, err = o.Delete(&{{.modelName}}{Id: id}); err == nil {
 			fmt.Println("Number of records deleted in database:", num)
 		}
 	}
 	return
 }
-`
+`)
@@ -0,0 +1,120 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/logger/colors"
+	"github.com/cisordeng/bee/utils"
+	"github.com/mattn/go-isatty"
+)
+
+// Plan is the list of the files a generator writes. The files are first planned,
+// then written at once by Apply, according to DryRun, Diff and Force:
+// an existing file is only overwritten with -force, or once confirmed.
+type Plan struct {
+	files []plannedFile
+}
+
+type plannedFile struct {
+	path    string
+	content string
+}
+
+// Add plans to write content to the file fpath. Go source code is formatted first.
+func (p *Plan) Add(fpath, content string) {
+	if strings.HasSuffix(fpath, ".go") {
+		if src, err := format.Source([]byte(content)); err == nil {
+			content = string(src)
+		} else {
+			beeLogger.Log.Warnf("Could not format '%s': %s", fpath, err)
+		}
+	}
+	p.files = append(p.files, plannedFile{path: fpath, content: content})
+}
+
+// Apply writes the planned files. With DryRun the files are only listed,
+// and with Diff the changes to the existing files are shown, without writing
+// anything. Otherwise the files which differ from the existing ones are
+// overwritten with Force, or once confirmed, or skipped if no one can confirm.
+func (p *Plan) Apply() {
+	w := colors.NewColorWriter(os.Stdout)
+
+	skipped := 0
+	for _, f := range p.files {
+		old, err := ioutil.ReadFile(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			beeLogger.Log.Fatalf("Could not read '%s': %s", f.path, err)
+		}
+		exists := err == nil
+
+		switch {
+		case !exists:
+			printAction(w, "\x1b[32m", "create", f.path)
+		case string(old) == f.content:
+			printAction(w, "\x1b[34m", "identical", f.path)
+			continue
+		case DryRun || Diff:
+			if Force {
+				printAction(w, "\x1b[33m", "overwrite", f.path)
+			} else {
+				printAction(w, "\x1b[31m", "conflict", f.path)
+			}
+			if Diff {
+				fmt.Fprint(w, utils.UnifiedDiff(f.path, f.path, string(old), f.content))
+			}
+		case Force || confirmOverwrite(f.path):
+			printAction(w, "\x1b[33m", "overwrite", f.path)
+		default:
+			printAction(w, "\x1b[33m", "skip", f.path)
+			skipped++
+			continue
+		}
+		if DryRun || Diff {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+			beeLogger.Log.Fatalf("Could not create the directory of '%s': %s", f.path, err)
+		}
+		utils.WriteToFile(f.path, f.content)
+	}
+	if skipped > 0 {
+		beeLogger.Log.Warnf("Skipped %d existing file(s). Use -diff to see the changes, and -force to overwrite them", skipped)
+	}
+}
+
+// confirmOverwrite asks whether to overwrite the existing file fpath,
+// unless the standard input is not a terminal to answer from
+func confirmOverwrite(fpath string) bool {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false
+	}
+	beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
+	return utils.AskForConfirmation()
+}
+
+// printAction prints what is done with the file fpath
+func printAction(w io.Writer, color, action, fpath string) {
+	fmt.Fprintf(w, "\t%s%s%s%s\t %s%s\n", color, "\x1b[1m", action, "\x1b[21m", fpath, "\x1b[0m")
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanAdd(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"main.go", "package main\nfunc main(){\n}", "package main\n\nfunc main() {\n}\n"},
+		{"broken.go", "package main\nfunc main(){", "package main\nfunc main(){"},
+		{"app.conf", "appname =  hello", "appname =  hello"},
+	}
+	for _, tt := range tests {
+		p := &Plan{}
+		p.Add(tt.path, tt.content)
+		if len(p.files) != 1 || p.files[0].path != tt.path || p.files[0].content != tt.want {
+			t.Errorf("Add(%q, %q) planned %+v, want content %q", tt.path, tt.content, p.files, tt.want)
+		}
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	dryRun, diff, force, stdin := DryRun, Diff, Force, os.Stdin
+	defer func() { DryRun, Diff, Force, os.Stdin = dryRun, diff, force, stdin }()
+
+	// Nobody can confirm the overwrites without a terminal
+	in, err := ioutil.TempFile(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	os.Stdin = in
+
+	tests := []struct {
+		name                string
+		dryRun, diff, force bool
+		old                 string // Content of the existing file, if not empty
+		want                string // Content of the file after Apply, empty if it does not exist
+	}{
+		{"create", false, false, false, "", "new"},
+		{"identical", false, false, false, "new", "new"},
+		{"skip without confirmation", false, false, false, "old", "old"},
+		{"overwrite with force", false, false, true, "old", "new"},
+		{"dry run does not create", true, false, false, "", ""},
+		{"dry run does not overwrite", true, false, true, "old", "old"},
+		{"diff does not create", false, true, false, "", ""},
+		{"diff does not overwrite", false, true, true, "old", "old"},
+	}
+	for _, tt := range tests {
+		DryRun, Diff, Force = tt.dryRun, tt.diff, tt.force
+		fpath := filepath.Join(t.TempDir(), "views", "index.tpl")
+		if tt.old != "" {
+			os.MkdirAll(filepath.Dir(fpath), 0755)
+			if err := ioutil.WriteFile(fpath, []byte(tt.old), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		p := &Plan{}
+		p.Add(fpath, "new")
+		p.Apply()
+
+		got, err := ioutil.ReadFile(fpath)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: file contains %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -15,19 +15,53 @@
 package generate
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"go/token"
+	"go/types"
 	"path"
 	"strings"
 
 	beeLogger "github.com/cisordeng/bee/logger"
-	"github.com/cisordeng/bee/logger/colors"
 	"github.com/cisordeng/bee/utils"
 )
 
-var restOne= `package {{.package_name}}
+// resourceTemplateDoc describes the data of the resource templates
+const resourceTemplateDoc = `
+Data of the templates of "bee generate resource", e.g. for account.user_info:
+
+  .app_name       Import path of the application
+  .package_name   account        .PackageName   Account        .packageName   account
+  .resource_name  user_info      .ResourceName  UserInfo       .resourceName  userInfo
+  .table_name     Name of the table of the model, e.g. account_user_info
+  .id_tag         Struct tag of the Id field of the model, if the primary key is not an auto "id"
+  .order_by       Order of the paged list, e.g. -created_at
+  .crud           Whether -crud is set, to generate the Put, Post and Delete handlers
+  .has_time       Whether a field is a time.Time
+  .has_input_time Whether a field read from the request parameters, i.e. not Auto, is a time.Time
+  .fields         Fields of the resource. Each field has:
+                    .Name       Column name, e.g. created_at
+                    .Type       Go type, e.g. time.Time
+                    .Options    ORM tag options, e.g. size(64)
+                    .Comment    Column comment
+                    .Auto       Whether the value is set by the ORM, e.g. auto_now_add
+                    .FieldName  Name in the structs, e.g. CreatedAt
+                    .VarName    Name as a variable, e.g. createdAt, with a "_" suffix
+                                if it collides with another name of the generated files
+                    .Tag        Struct tag of the field in the model
+                    .Getter     Statement reading the field from the request parameters
+                    .IsTime     Whether the type is time.Time
+                    .IsUnique   Whether the field has the unique ORM option
+
+Functions: camel, snake, title, lower and upper.
+`
+
+var restOne = NewTemplate("resource", "rest_one", resourceTemplateDoc, `package {{.package_name}}
 
 import (
+{{- if and .crud .has_input_time}}
+	"time"
+{{end}}
 	"github.com/cisordeng/beego/xenon"
 
 	b{{.PackageName}} "{{.app_name}}/business/{{.package_name}}"
@@ -50,6 +84,22 @@ func (this *{{.ResourceName}}) Params() map[string][]string {
 		"GET":  []string{
 			"id",
 		},
+{{- if .crud}}
+		"PUT": []string{
+{{- range .fields}}{{if not .Auto}}
+			"{{.Name}}",
+{{- end}}{{end}}
+		},
+		"POST": []string{
+			"id",
+{{- range .fields}}{{if not .Auto}}
+			"{{.Name}}",
+{{- end}}{{end}}
+		},
+		"DELETE": []string{
+			"id",
+		},
+{{- end}}
 	}
 }
 
@@ -63,9 +113,51 @@ func (this *{{.ResourceName}}) Get() {
 	data := b{{.PackageName}}.Encode{{.ResourceName}}({{.resourceName}})
 	this.ReturnJSON(data)
 }
-`
+{{- if .crud}}
+
+func (this *{{.ResourceName}}) Put() {
+{{- range .fields}}{{if not .Auto}}
+	{{.Getter}}
+{{- end}}{{end}}
 
-var restComplex = `package {{.package_name}}
+	bCtx := this.GetBusinessContext()
+
+	{{.resourceName}} := b{{.PackageName}}.New{{.ResourceName}}(bCtx{{range .fields}}{{if not .Auto}}, {{.VarName}}{{end}}{{end}})
+	data := b{{.PackageName}}.Encode{{.ResourceName}}({{.resourceName}})
+	this.ReturnJSON(data)
+}
+
+func (this *{{.ResourceName}}) Post() {
+	id, _ := this.GetInt("id", 0)
+{{- range .fields}}{{if not .Auto}}
+	{{.Getter}}
+{{- end}}{{end}}
+
+	bCtx := this.GetBusinessContext()
+
+	repository := b{{.PackageName}}.New{{.ResourceName}}Repository(bCtx)
+	{{.resourceName}} := repository.Get{{.ResourceName}}ById(id)
+{{- range .fields}}{{if not .Auto}}
+	{{$.resourceName}}.{{.FieldName}} = {{.VarName}}
+{{- end}}{{end}}
+	{{.resourceName}}.Save()
+	data := b{{.PackageName}}.Encode{{.ResourceName}}({{.resourceName}})
+	this.ReturnJSON(data)
+}
+
+func (this *{{.ResourceName}}) Delete() {
+	id, _ := this.GetInt("id", 0)
+
+	bCtx := this.GetBusinessContext()
+
+	repository := b{{.PackageName}}.New{{.ResourceName}}Repository(bCtx)
+	repository.Delete{{.ResourceName}}ById(id)
+	this.ReturnJSON(xenon.Map{})
+}
+{{- end}}
+`)
+
+var restComplex = NewTemplate("resource", "rest_complex", resourceTemplateDoc, `package {{.package_name}}
 
 import (
 	"github.com/cisordeng/beego/xenon"
@@ -96,7 +188,7 @@ func (this *{{.ResourceName}}s) Get() {
 	page := this.GetPage()
 
 	repository := b{{.PackageName}}.New{{.ResourceName}}Repository(bCtx)
-	{{.resourceName}}s, pageInfo := repository.GetPaged{{.ResourceName}}s(page, xenon.Map{}, "-created_at")
+	{{.resourceName}}s, pageInfo := repository.GetPaged{{.ResourceName}}s(page, xenon.Map{}, "{{.order_by}}")
 	data := b{{.PackageName}}.EncodeMany{{.ResourceName}}({{.resourceName}}s)
 	this.ReturnJSON(xenon.Map{
 		"{{.resource_name}}s": data,
@@ -104,13 +196,15 @@ func (this *{{.ResourceName}}s) Get() {
 	})
 }
 
-`
+`)
 
-var businessEntity = `package {{.package_name}}
+var businessEntity = NewTemplate("resource", "entity", resourceTemplateDoc, `package {{.package_name}}
 
 import (
 	"context"
+{{- if .has_time}}
 	"time"
+{{- end}}
 
 	"github.com/cisordeng/beego/xenon"
 
@@ -119,9 +213,11 @@ import (
 
 type {{.ResourceName}} struct {
 	xenon.Entity
-	
+
 	Id int
-	CreatedAt time.Time
+{{- range .fields}}
+	{{.FieldName}} {{.Type}}
+{{- end}}
 }
 
 func init() {
@@ -131,16 +227,19 @@ func Init{{.ResourceName}}FromModel(ctx context.Context, model *m{{.PackageName}
 	instance := new({{.ResourceName}})
 	instance.Ctx = ctx
 	instance.Id = model.Id
-	
-	instance.CreatedAt = model.CreatedAt
+{{- range .fields}}
+	instance.{{.FieldName}} = model.{{.FieldName}}
+{{- end}}
 
 	return instance
 }
 
-func New{{.ResourceName}}(ctx context.Context) *{{.ResourceName}} {
+func New{{.ResourceName}}(ctx context.Context{{range .fields}}{{if not .Auto}}, {{.VarName}} {{.Type}}{{end}}{{end}}) *{{.ResourceName}} {
 	o := xenon.GetOrmFromContext(ctx)
 	model := m{{.PackageName}}.{{.ResourceName}}{
-		
+{{- range .fields}}{{if not .Auto}}
+		{{.FieldName}}: {{.VarName}},
+{{- end}}{{end}}
 	}
 	_, err := o.Insert(&model)
 	xenon.PanicNotNilError(err)
@@ -159,14 +258,29 @@ func New{{.ResourceName}}s(ctx context.Context, models []*m{{.PackageName}}.{{.R
 	}
 	return {{.resourceName}}s
 }
-`
+{{- if .crud}}
 
-var businessRepository = `package {{.package_name}}
+func (this *{{.ResourceName}}) Save() {
+	o := xenon.GetOrmFromContext(this.Ctx)
+	model := m{{.PackageName}}.{{.ResourceName}}{
+		Id: this.Id,
+{{- range .fields}}
+		{{.FieldName}}: this.{{.FieldName}},
+{{- end}}
+	}
+	_, err := o.Update(&model)
+	xenon.PanicNotNilError(err, "raise:{{.resource_name}}:update_failed", "更新失败")
+}
+{{- end}}
+`)
+
+var businessRepository = NewTemplate("resource", "repository", resourceTemplateDoc, `package {{.package_name}}
 
 import (
 	"context"
 
-	"github.com/cisordeng/beego/xenon"
+	{{if .crud}}"github.com/cisordeng/beego/orm"
+	{{end}}"github.com/cisordeng/beego/xenon"
 
 	m{{.PackageName}} "{{.app_name}}/model/{{.package_name}}"
 )
@@ -245,9 +359,64 @@ func (this *{{.ResourceName}}Repository) Get{{.ResourceName}}ById(id int) *{{.Re
 		"id": id,
 	})
 }
-`
+{{- range .fields}}{{if not .IsTime}}{{/* Exact matches on a time are rarely useful */}}
+{{- if .IsUnique}}
+
+func (this *{{$.ResourceName}}Repository) Get{{$.ResourceName}}By{{.FieldName}}({{.VarName}} {{.Type}}) *{{$.ResourceName}} {
+	return this.GetOne{{$.ResourceName}}(xenon.Map{
+		"{{.Name}}": {{.VarName}},
+	})
+}
+{{- else}}
+
+func (this *{{$.ResourceName}}Repository) Get{{$.ResourceName}}sBy{{.FieldName}}({{.VarName}} {{.Type}}) []*{{$.ResourceName}} {
+	return this.Get{{$.ResourceName}}s(xenon.Map{
+		"{{.Name}}": {{.VarName}},
+	})
+}
+{{- end}}
+{{- end}}{{end}}
+{{- if .crud}}
+
+func (this *{{.ResourceName}}Repository) Update{{.ResourceName}}s(filters xenon.Map, values xenon.Map) int64 {
+	o := xenon.GetOrmFromContext(this.Ctx)
+	qs := o.QueryTable(&m{{.PackageName}}.{{.ResourceName}}{})
+
+	if len(filters) > 0 {
+		qs = qs.Filter(filters)
+	}
+
+	params := orm.Params{}
+	for k, v := range values {
+		params[k] = v
+	}
+	num, err := qs.Update(params)
+	xenon.PanicNotNilError(err, "raise:{{.resource_name}}:update_failed", "更新失败")
+	return num
+}
+
+func (this *{{.ResourceName}}Repository) Delete{{.ResourceName}}s(filters xenon.Map) int64 {
+	o := xenon.GetOrmFromContext(this.Ctx)
+	qs := o.QueryTable(&m{{.PackageName}}.{{.ResourceName}}{})
+
+	if len(filters) > 0 {
+		qs = qs.Filter(filters)
+	}
+
+	num, err := qs.Delete()
+	xenon.PanicNotNilError(err, "raise:{{.resource_name}}:delete_failed", "删除失败")
+	return num
+}
 
-var businessEncode = `package {{.package_name}}
+func (this *{{.ResourceName}}Repository) Delete{{.ResourceName}}ById(id int) {
+	this.Delete{{.ResourceName}}s(xenon.Map{
+		"id": id,
+	})
+}
+{{- end}}
+`)
+
+var businessEncode = NewTemplate("resource", "encode", resourceTemplateDoc, `package {{.package_name}}
 import (
 	"github.com/cisordeng/beego/xenon"
 )
@@ -259,8 +428,13 @@ func Encode{{.ResourceName}}({{.resourceName}} *{{.ResourceName}}) xenon.Map {
 
 	map{{.ResourceName}} := xenon.Map{
 		"id": {{.resourceName}}.Id,
-		
-		"created_at": {{.resourceName}}.CreatedAt.Format("2006-01-02 15:04:05"),
+{{- range .fields}}
+{{- if .IsTime}}
+		"{{.Name}}": {{$.resourceName}}.{{.FieldName}}.Format("2006-01-02 15:04:05"),
+{{- else}}
+		"{{.Name}}": {{$.resourceName}}.{{.FieldName}},
+{{- end}}
+{{- end}}
 	}
 	return map{{.ResourceName}}
 }
@@ -273,45 +447,38 @@ func EncodeMany{{.ResourceName}}({{.resourceName}}s []*{{.ResourceName}}) []xeno
 	}
 	return map{{.ResourceName}}s
 }
-`
+`)
 
-var model = `package {{.package_name}}
+var model = NewTemplate("resource", "model", resourceTemplateDoc, `package {{.package_name}}
 
 import (
+{{- if .has_time}}
 	"time"
-	
+{{end}}
 	"github.com/cisordeng/beego/orm"
 )
 
 type {{.ResourceName}} struct {
-	Id int
-	
-	CreatedAt time.Time ` + "`orm:\"auto_now_add;type(datetime)\"`" + `
+	Id int{{.id_tag}}
+{{- range .fields}}
+	{{.FieldName}} {{.Type}}{{.Tag}}
+{{- end}}
 }
 
 func (o *{{.ResourceName}}) TableName() string {
-	return "{{.package_name}}_{{.resource_name}}"
+	return "{{.table_name}}"
 }
 
 func init() {
 	orm.RegisterModel(new({{.ResourceName}}))
 }
-`
+`)
 
-func GenerateResource(cname, currpath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
-	inGoPath := ""
-	for _, goPath := range utils.GetGOPATHs() {
-		if strings.Contains(currpath, goPath) {
-			inGoPath = goPath
-			break
-		}
+func GenerateResource(cname, fields, currpath string) {
+	appName, err := utils.GetImportPath(currpath)
+	if err != nil {
+		beeLogger.Log.Fatalf("Wrong generate resource command, %s", err)
 	}
-	if inGoPath == "" {
-		beeLogger.Log.Fatal("Wrong generate resource command, current path is not $GOPATH")
-	}
-	appName := strings.Split(currpath[len(inGoPath) + 1:], "/")[1]
 	cname = strings.Replace(cname, ".", "/", -1)
 	p, f := path.Split(cname)
 	resourceName := strings.Title(f)
@@ -324,51 +491,334 @@ func GenerateResource(cname, currpath string) {
 		beeLogger.Log.Fatal("Wrong generate resource command, it should like [bee generate resource package/resource]")
 	}
 
+	resourceFields, err := parseResourceFields(fields)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not generate the resource fields: %s", err)
+	}
+	resourceFields = append(resourceFields, resourceField{
+		Name:    "created_at",
+		Type:    "time.Time",
+		Options: []string{"auto_now_add", "type(datetime)"},
+		Auto:    true,
+	})
+
 	beeLogger.Log.Infof("Using '%s' as resource name", utils.CamelString(resourceName))
 	beeLogger.Log.Infof("Using '%s' as package name", packageName)
 
-	// rest
-	restPath := path.Join(currpath, "rest", packageName)
-	os.MkdirAll(restPath, 0755)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(restPath, fmt.Sprintf("%s.go", strings.ToLower(resourceName))), "\x1b[0m")
-	utils.WriteToFile(path.Join(restPath, fmt.Sprintf("%s.go", strings.ToLower(resourceName))),
-		replaceTpl(restOne, appName, packageName, strings.ToLower(resourceName)))
+	plan := &Plan{}
+	addResourceFiles(plan, appName, currpath, &resource{
+		packageName:  packageName,
+		resourceName: strings.ToLower(resourceName),
+		tableName:    packageName + "_" + strings.ToLower(resourceName),
+		fields:       resourceFields,
+	})
+	plan.Apply()
+}
 
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(restPath, fmt.Sprintf("%ss.go", strings.ToLower(resourceName))), "\x1b[0m")
-	utils.WriteToFile(path.Join(restPath, fmt.Sprintf("%ss.go", strings.ToLower(resourceName))),
-		replaceTpl(restComplex, appName, packageName, strings.ToLower(resourceName)))
+// resource describes a generated resource
+type resource struct {
+	packageName  string
+	resourceName string // Lower case name, e.g. user or user_info
+	tableName    string
+	idTag        string // ORM tag of the primary key, empty for an auto increment "id" column
+	fields       []resourceField
+}
 
-	// business
-	businessPath := path.Join(currpath, "business", packageName)
-	os.MkdirAll(businessPath, 0755)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(businessPath, fmt.Sprintf("%s.go", strings.ToLower(resourceName))), "\x1b[0m")
-	utils.WriteToFile(path.Join(businessPath, fmt.Sprintf("%s.go", strings.ToLower(resourceName))),
-		replaceTpl(businessEntity, appName, packageName, strings.ToLower(resourceName)))
+// addResourceFiles plans the rest, business and model files of a resource
+func addResourceFiles(plan *Plan, appName, currpath string, r *resource) {
+	data := r.templateData(appName, CRUD)
+	filename := getFileName(r.resourceName)
 
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(businessPath, fmt.Sprintf("%s_repository.go", strings.ToLower(resourceName))), "\x1b[0m")
-	utils.WriteToFile(path.Join(businessPath, fmt.Sprintf("%s_repository.go", strings.ToLower(resourceName))),
-		replaceTpl(businessRepository, appName, packageName, strings.ToLower(resourceName)))
+	// rest
+	restPath := path.Join(currpath, "rest", r.packageName)
+	plan.Add(path.Join(restPath, fmt.Sprintf("%s.go", filename)), restOne.Render(currpath, data))
+	plan.Add(path.Join(restPath, fmt.Sprintf("%ss.go", filename)), restComplex.Render(currpath, data))
 
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(businessPath, fmt.Sprintf("encode_%s.go", strings.ToLower(resourceName))), "\x1b[0m")
-	utils.WriteToFile(path.Join(businessPath, fmt.Sprintf("encode_%s.go", strings.ToLower(resourceName))),
-		replaceTpl(businessEncode, appName, packageName, strings.ToLower(resourceName)))
+	// business
+	businessPath := path.Join(currpath, "business", r.packageName)
+	plan.Add(path.Join(businessPath, fmt.Sprintf("%s.go", filename)), businessEntity.Render(currpath, data))
+	plan.Add(path.Join(businessPath, fmt.Sprintf("%s_repository.go", filename)), businessRepository.Render(currpath, data))
+	plan.Add(path.Join(businessPath, fmt.Sprintf("encode_%s.go", filename)), businessEncode.Render(currpath, data))
 
 	// model
-	modelPath := path.Join(currpath, "model", packageName)
-	os.MkdirAll(modelPath, 0755)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(modelPath, fmt.Sprintf("%s.go", strings.ToLower(resourceName))), "\x1b[0m")
-	utils.WriteToFile(path.Join(modelPath, fmt.Sprintf("%s.go", strings.ToLower(resourceName))),
-		replaceTpl(model, appName, packageName, strings.ToLower(resourceName)))
+	modelPath := path.Join(currpath, "model", r.packageName)
+	plan.Add(path.Join(modelPath, fmt.Sprintf("%s.go", filename)), model.Render(currpath, data))
 }
 
-func replaceTpl(tpl string, app string, package_name string, resource_name string) string {
-	PackageName := utils.CamelCase(package_name)
-	packageName := string(package_name[0]) + PackageName[1:]
+// resourceField is a field of a generated resource, given as name:type[:option...]
+// with -fields, e.g. "email:string:size(64):unique", or read from a table column
+type resourceField struct {
+	Name    string   // Column name, e.g. created_by
+	Type    string   // Go type, e.g. int64
+	Options []string // ORM tag options, e.g. size(64)
+	Comment string   // Column comment
+	Auto    bool     // Whether the value is set by the ORM, e.g. auto_now_add, rather than by the API
+
+	varName string // Name as a variable, set by templateData
+}
 
-	ResourceName := utils.CamelCase(resource_name)
-	resourceName := string(resource_name[0]) + ResourceName[1:]
+// parseResourceFields parses the fields of a resource, e.g.
+// "name:string:size(64),age:int,email:string:unique"
+func parseResourceFields(fields string) ([]resourceField, error) {
+	var rfs []resourceField
+	if strings.TrimSpace(fields) == "" {
+		return rfs, nil
+	}
+	seen := make(map[string]bool)
+	for _, v := range strings.Split(fields, ",") {
+		parts := strings.Split(strings.TrimSpace(v), ":")
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, errors.New("the fields format is wrong. Should be name:type[:option...],name:type " + v)
+		}
+		name := utils.SnakeString(parts[0])
+		if reservedFieldNames[utils.CamelCase(name)] {
+			return nil, fmt.Errorf("the field '%s' is always generated, or collides with a member of the generated structs", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("the field '%s' is given twice", name)
+		}
+		seen[name] = true
 
-	a := strings.Replace(tpl, "{{.app_name}}", app, -1)
-	p := strings.Replace(strings.Replace(strings.Replace(a, "{{.package_name}}", package_name, -1), "{{.packageName}}", packageName, -1), "{{.PackageName}}", PackageName, -1)
-	return strings.Replace(strings.Replace(strings.Replace(p, "{{.resource_name}}", resource_name, -1), "{{.resourceName}}", resourceName, -1), "{{.ResourceName}}", ResourceName, -1)
+		typ, options := getResourceType(parts[1])
+		if typ == "" {
+			return nil, fmt.Errorf("unknown type '%s' of the field '%s'", parts[1], name)
+		}
+		for _, option := range parts[2:] {
+			if option != "" {
+				options = append(options, option)
+			}
+		}
+		rfs = append(rfs, resourceField{Name: name, Type: typ, Options: options})
+	}
+	return rfs, nil
+}
+
+// reservedFieldNames are the fields and methods of the generated structs
+var reservedFieldNames = map[string]bool{"Id": true, "CreatedAt": true, "Ctx": true, "Entity": true, "Save": true, "TableName": true}
+
+// getResourceType returns the Go type of a field type and its default ORM options
+func getResourceType(ftype string) (string, []string) {
+	switch ftype {
+	case "string":
+		return "string", nil
+	case "text":
+		return "string", []string{"type(text)"}
+	case "datetime", "time":
+		return "time.Time", []string{"type(datetime)"}
+	case "float":
+		return "float64", nil
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"bool", "float32", "float64":
+		return ftype, nil
+	}
+	return "", nil
+}
+
+// tableResource returns the resource of a table read from the database. The package and
+// the name of the resource are taken from the table name, e.g. account_user is account.user.
+func tableResource(tb *Table) (*resource, error) {
+	if tb.Pk == "" {
+		return nil, errors.New("it has no primary key, or a composite one")
+	}
+
+	name := strings.ToLower(tb.Name)
+	r := &resource{packageName: name, resourceName: name, tableName: tb.Name}
+	if i := strings.Index(name, "_"); i > 0 && i < len(name)-1 {
+		r.packageName, r.resourceName = name[:i], name[i+1:]
+	}
+
+	for _, col := range tb.Columns {
+		tag := *col.Tag
+		if tag.Column == tb.Pk {
+			if tb.Pk != "id" || !tag.Auto {
+				r.idTag = " " + tag.String()
+			}
+			continue
+		}
+		if col.Name == "Id_RENAME" {
+			beeLogger.Log.Warnf("Skipping the column 'id' of '%s', which is not its primary key", tb.Name)
+			continue
+		}
+
+		typ := col.Type
+		if tag.RelFk {
+			// The referenced table is a resource of its own, so only its id is kept
+			typ = "int"
+			tag.RelFk = false
+		}
+		r.fields = append(r.fields, resourceField{
+			Name:    utils.SnakeString(col.Name),
+			Type:    typ,
+			Options: tag.options(),
+			Comment: tag.Comment,
+			Auto:    tag.AutoNow || tag.AutoNowAdd,
+		})
+	}
+	return r, nil
+}
+
+// writeTableResources writes the rest, business and model files of the tables
+func writeTableResources(tables []*Table, apppath string) {
+	appName := getPackagePath(apppath)
+	plan := &Plan{}
+	for _, tb := range tables {
+		r, err := tableResource(tb)
+		if err != nil {
+			beeLogger.Log.Warnf("Skipping the table '%s': %s", tb.Name, err)
+			continue
+		}
+		beeLogger.Log.Infof("Using '%s.%s' as resource of the table '%s'", r.packageName, r.resourceName, tb.Name)
+		addResourceFiles(plan, appName, apppath, r)
+	}
+	plan.Apply()
+}
+
+// FieldName returns the name of the field in the structs, e.g. CreatedBy
+func (f resourceField) FieldName() string {
+	return utils.CamelCase(f.Name)
+}
+
+// VarName returns the name of the field as a variable, e.g. createdBy
+func (f resourceField) VarName() string {
+	if f.varName != "" {
+		return f.varName
+	}
+	name := utils.CamelString(f.Name)
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// needsTempVar reports whether Getter reads the field into a temporary
+// variable named after it, e.g. price64, before converting it
+func (f resourceField) needsTempVar() bool {
+	return f.Type == "float32" || f.Type == "uint"
+}
+
+// varNames returns the names of the fields as variables. A "_" suffix is added
+// to the names colliding with a keyword, a predeclared identifier, a variable
+// or an import of the generated files, or with another field.
+func (r *resource) varNames(crud bool) []string {
+	PackageName := utils.CamelCase(r.packageName)
+	ResourceName := utils.CamelCase(r.resourceName)
+	resourceName := string(r.resourceName[0]) + ResourceName[1:]
+
+	used := map[string]bool{
+		// Variables of the handlers, the entity and the repository
+		"this": true, "data": true, "repository": true, "bCtx": true, "ctx": true,
+		"o": true, "model": true, "models": true, "err": true, "instance": true,
+		resourceName: true, resourceName + "s": true,
+		// Imports
+		"context": true, "time": true, "xenon": true, "orm": true,
+		"b" + PackageName: true, "m" + PackageName: true,
+	}
+	if crud {
+		used["id"] = true
+	}
+	isFree := func(name string) bool {
+		return !used[name] && !token.Lookup(name).IsKeyword() && types.Universe.Lookup(name) == nil
+	}
+
+	names := make([]string, len(r.fields))
+	for i, f := range r.fields {
+		name := f.VarName()
+		for !isFree(name) || f.needsTempVar() && !isFree(name+"64") {
+			name += "_"
+		}
+		used[name] = true
+		if f.needsTempVar() {
+			used[name+"64"] = true
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// Tag returns the struct tag of the field in the model
+func (f resourceField) Tag() string {
+	var tags []string
+	if len(f.Options) > 0 {
+		tags = append(tags, fmt.Sprintf("orm:\"%s\"", strings.Join(f.Options, ";")))
+	}
+	if f.Comment != "" {
+		tags = append(tags, fmt.Sprintf("description:\"%s\"", f.Comment))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return " `" + strings.Join(tags, " ") + "`"
+}
+
+// Getter returns the statement which reads the field from the request parameters
+func (f resourceField) Getter() string {
+	switch f.Type {
+	case "string":
+		return fmt.Sprintf("%s := this.GetString(\"%s\", \"\")", f.VarName(), f.Name)
+	case "time.Time":
+		return fmt.Sprintf("%s, _ := time.Parse(\"2006-01-02 15:04:05\", this.GetString(\"%s\", \"\"))", f.VarName(), f.Name)
+	case "bool":
+		return fmt.Sprintf("%s, _ := this.GetBool(\"%s\", false)", f.VarName(), f.Name)
+	case "float64":
+		return fmt.Sprintf("%s, _ := this.GetFloat(\"%s\", 0)", f.VarName(), f.Name)
+	case "float32":
+		return fmt.Sprintf("%s, _ := this.GetFloat(\"%s\", 0)\n\t%s := float32(%s)", f.VarName()+"64", f.Name, f.VarName(), f.VarName()+"64")
+	case "uint":
+		return fmt.Sprintf("%s, _ := this.GetUint64(\"%s\", 0)\n\t%s := uint(%s)", f.VarName()+"64", f.Name, f.VarName(), f.VarName()+"64")
+	}
+	// int, int8, ..., uint64
+	return fmt.Sprintf("%s, _ := this.Get%s(\"%s\", 0)", f.VarName(), strings.Title(f.Type), f.Name)
+}
+
+// IsTime reports whether the field is a time.Time
+func (f resourceField) IsTime() bool {
+	return f.Type == "time.Time"
+}
+
+// IsUnique reports whether the field has the unique ORM option
+func (f resourceField) IsUnique() bool {
+	for _, option := range f.Options {
+		if option == "unique" || option == "pk" {
+			return true
+		}
+	}
+	return false
+}
+
+// templateData returns the data the resource templates are rendered with
+func (r *resource) templateData(appName string, crud bool) map[string]interface{} {
+	PackageName := utils.CamelCase(r.packageName)
+	ResourceName := utils.CamelCase(r.resourceName)
+
+	hasTime, hasInputTime := false, false
+	orderBy := "-id"
+	for _, f := range r.fields {
+		if f.IsTime() {
+			hasTime = true
+			hasInputTime = hasInputTime || !f.Auto
+		}
+		if f.Name == "created_at" {
+			orderBy = "-created_at"
+		}
+	}
+
+	fields := append([]resourceField(nil), r.fields...)
+	for i, name := range r.varNames(crud) {
+		fields[i].varName = name
+	}
+
+	return map[string]interface{}{
+		"app_name":       appName,
+		"package_name":   r.packageName,
+		"packageName":    string(r.packageName[0]) + PackageName[1:],
+		"PackageName":    PackageName,
+		"resource_name":  r.resourceName,
+		"resourceName":   string(r.resourceName[0]) + ResourceName[1:],
+		"ResourceName":   ResourceName,
+		"table_name":     r.tableName,
+		"id_tag":         r.idTag,
+		"order_by":       orderBy,
+		"crud":           crud,
+		"has_time":       hasTime,
+		"has_input_time": hasInputTime,
+		"fields":         fields,
+	}
 }
@@ -0,0 +1,225 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseResourceFields(t *testing.T) {
+	tests := []struct {
+		fields string
+		want   []resourceField
+		err    bool
+	}{
+		{"", nil, false},
+		{
+			"name:string:size(64),age:int,email:string:unique",
+			[]resourceField{
+				{Name: "name", Type: "string", Options: []string{"size(64)"}},
+				{Name: "age", Type: "int"},
+				{Name: "email", Type: "string", Options: []string{"unique"}},
+			},
+			false,
+		},
+		{
+			"body:text,bornAt:datetime,price:float",
+			[]resourceField{
+				{Name: "body", Type: "string", Options: []string{"type(text)"}},
+				{Name: "born_at", Type: "time.Time", Options: []string{"type(datetime)"}},
+				{Name: "price", Type: "float64"},
+			},
+			false,
+		},
+		{"name", nil, true},
+		{"name:complex", nil, true},
+		{"name:string,name:int", nil, true},
+		{"id:int", nil, true},
+		{"created_at:datetime", nil, true},
+		{"ctx:int", nil, true},
+		{"save:bool", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseResourceFields(tt.fields)
+		if (err != nil) != tt.err {
+			t.Errorf("parseResourceFields(%q) error = %v, want error %v", tt.fields, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseResourceFields(%q) = %+v, want %+v", tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestVarNames(t *testing.T) {
+	tests := []struct {
+		fields string
+		crud   bool
+		want   []string
+	}{
+		{"name:string,created_by:int", false, []string{"name", "createdBy"}},
+		{"id_card:string", true, []string{"idCard"}},
+		{"type:string,len:int,time:datetime,xenon:string", false, []string{"type_", "len_", "time_", "xenon_"}},
+		{"user:string,users:int,b_account:int,m_account:int", false, []string{"user_", "users_", "bAccount_", "mAccount_"}},
+		{"price:float32,price64:float64", false, []string{"price", "price64_"}},
+		{"price64:float64,price:float32", false, []string{"price64", "price_"}},
+		{"data:string,data_:string", false, []string{"data_", "data__"}},
+	}
+	for _, tt := range tests {
+		fields, err := parseResourceFields(tt.fields)
+		if err != nil {
+			t.Fatalf("parseResourceFields(%q): %s", tt.fields, err)
+		}
+		r := &resource{packageName: "account", resourceName: "user", fields: fields}
+		if got := r.varNames(tt.crud); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("varNames(%q, crud %v) = %v, want %v", tt.fields, tt.crud, got, tt.want)
+		}
+	}
+}
+
+// beegoStubs are the parts of the beego packages used by the generated resources
+var beegoStubs = map[string]string{
+	"go.mod": "module github.com/cisordeng/beego\n",
+	"orm/orm.go": `package orm
+
+type Params map[string]interface{}
+
+type QuerySeter interface {
+	Filter(args ...interface{}) QuerySeter
+	OrderBy(exprs ...string) QuerySeter
+	One(container interface{}, cols ...string) error
+	All(container interface{}, cols ...string) (int64, error)
+	Update(values Params) (int64, error)
+	Delete() (int64, error)
+}
+
+type Ormer interface {
+	QueryTable(ptr interface{}) QuerySeter
+	Insert(md interface{}) (int64, error)
+	InsertMulti(bulk int, mds interface{}) (int64, error)
+	Update(md interface{}, cols ...string) (int64, error)
+}
+
+func RegisterModel(models ...interface{}) {}
+`,
+	"xenon/xenon.go": `package xenon
+
+import (
+	"context"
+
+	"github.com/cisordeng/beego/orm"
+)
+
+type Map map[string]interface{}
+type Entity struct{ Ctx context.Context }
+type Repository struct{ Ctx context.Context }
+type Paginator struct{}
+type PageInfo struct{}
+
+func (PageInfo) ToMap() Map { return nil }
+
+type RestResource struct{}
+
+func (*RestResource) GetString(key string, def ...string) string           { return "" }
+func (*RestResource) GetInt(key string, def ...int) (int, error)           { return 0, nil }
+func (*RestResource) GetInt8(key string, def ...int8) (int8, error)        { return 0, nil }
+func (*RestResource) GetInt16(key string, def ...int16) (int16, error)     { return 0, nil }
+func (*RestResource) GetInt32(key string, def ...int32) (int32, error)     { return 0, nil }
+func (*RestResource) GetInt64(key string, def ...int64) (int64, error)     { return 0, nil }
+func (*RestResource) GetUint8(key string, def ...uint8) (uint8, error)     { return 0, nil }
+func (*RestResource) GetUint16(key string, def ...uint16) (uint16, error)  { return 0, nil }
+func (*RestResource) GetUint32(key string, def ...uint32) (uint32, error)  { return 0, nil }
+func (*RestResource) GetUint64(key string, def ...uint64) (uint64, error)  { return 0, nil }
+func (*RestResource) GetBool(key string, def ...bool) (bool, error)        { return false, nil }
+func (*RestResource) GetFloat(key string, def ...float64) (float64, error) { return 0, nil }
+func (*RestResource) GetBusinessContext() context.Context                  { return nil }
+func (*RestResource) GetPage() *Paginator                                  { return nil }
+func (*RestResource) ReturnJSON(data interface{})                          {}
+
+func RegisterResource(r interface{})                  {}
+func GetOrmFromContext(ctx context.Context) orm.Ormer { return nil }
+func PanicNotNilError(err error, args ...string)      {}
+
+func Paginate(qs orm.QuerySeter, page *Paginator, c interface{}) (PageInfo, error) {
+	return PageInfo{}, nil
+}
+`,
+}
+
+// TestGenerateResourceBuilds generates resources into a module using stubs
+// of the beego packages, and checks that the generated code compiles
+func TestGenerateResourceBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	tests := []struct {
+		name   string
+		fields string
+		crud   bool
+	}{
+		{"account.user", "", false},
+		{"account.user", "", true},
+		{"account.user", "name:string:size(64),age:int,email:string:unique", false},
+		{"account.user", "name:string:size(64),age:int,email:string:unique", true},
+		{"shop.order", "born:datetime,price:float32,count:uint,paid:bool,rate:float,small:int8", true},
+		{"shop.order", "user:string,order:int,time:datetime,xenon:string,orm:string,string:string,len:int," +
+			"price:float32,price64:float64,context:string,b_shop:string,m_shop:int,model:string,type:string,o:int,err:string", true},
+	}
+
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	defer func(crud bool) { CRUD = crud }(CRUD)
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		files := map[string]string{
+			"go.mod": "module example.com/app\n\ngo 1.13\n\nrequire github.com/cisordeng/beego v0.0.0\n\n" +
+				"replace github.com/cisordeng/beego => ./beego\n",
+		}
+		for name, content := range beegoStubs {
+			files[filepath.Join("beego", name)] = content
+		}
+		for name, content := range files {
+			fpath := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(fpath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		CRUD = tt.crud
+		GenerateResource(tt.name, tt.fields, dir)
+
+		for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+			cmd := exec.Command("go", args...)
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("go %s of %s -fields=%q (crud %v): %s\n%s", args[0], tt.name, tt.fields, tt.crud, err, out)
+			}
+		}
+	}
+}
@@ -1,14 +1,13 @@
 package generate
 
 import (
-	"strings"
-
-	"github.com/cisordeng/bee/cmd/commands/migrate"
 	beeLogger "github.com/cisordeng/bee/logger"
 	"github.com/cisordeng/bee/utils"
 )
 
-func GenerateScaffold(sname, fields, currpath, driver, conn string) {
+// GenerateScaffold generates the model, the controller, the views and the migration
+// of a resource, each after a confirmation. The caller may then run the migration.
+func GenerateScaffold(sname, fields, currpath string) {
 	beeLogger.Log.Infof("Do you want to create a '%s' model? [Yes|No] ", sname)
 
 	// Generate the model
@@ -40,11 +39,4 @@ func GenerateScaffold(sname, fields, currpath, driver, conn string) {
 		}
 		GenerateMigration(sname, upsql, downsql, currpath)
 	}
-
-	// Run the migration
-	beeLogger.Log.Infof("Do you want to migrate the database? [Yes|No] ")
-	if utils.AskForConfirmation() {
-		migrate.MigrateUpdate(currpath, driver, conn, "")
-	}
-	beeLogger.Log.Successf("All done! Don't forget to add  beego.Router(\"/%s\" ,&controllers.%sController{}) to routers/route.go\n", sname, strings.Title(sname))
 }
@@ -0,0 +1,138 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/bee/utils"
+)
+
+// TemplateExt is the extension of the files overriding the built-in templates
+const TemplateExt = ".tmpl"
+
+// Template is a built-in template of a generator, rendered with text/template.
+// It is overridden by a <generator>/<name>.tmpl file of a template directory.
+type Template struct {
+	Generator string
+	Name      string
+	Doc       string // Description of the data the template is rendered with
+	Text      string
+}
+
+// templates holds the built-in templates, in the order they were registered
+var templates []*Template
+
+// templateFuncs are the functions available in the templates, besides the built-in ones
+var templateFuncs = template.FuncMap{
+	"camel": utils.CamelCase,
+	"snake": utils.SnakeString,
+	"title": strings.Title,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// NewTemplate registers a built-in template of a generator
+func NewTemplate(generator, name, doc, text string) *Template {
+	t := &Template{Generator: generator, Name: name, Doc: doc, Text: text}
+	templates = append(templates, t)
+	return t
+}
+
+// Templates returns the built-in templates
+func Templates() []*Template {
+	return templates
+}
+
+// TemplateDirs returns the directories the templates are looked up in, by order of
+// precedence: the .bee/templates directory of the project, then the one of the user
+func TemplateDirs(currpath string) []string {
+	dirs := []string{filepath.Join(currpath, ".bee", "templates")}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".bee", "templates"))
+	}
+	return dirs
+}
+
+// File returns the path of the template relative to a template directory
+func (t *Template) File() string {
+	return filepath.Join(t.Generator, t.Name+TemplateExt)
+}
+
+// Lookup returns the text of the template, and the file it was read from,
+// which is empty if the template is not overridden
+func (t *Template) Lookup(currpath string) (text, file string) {
+	for _, dir := range TemplateDirs(currpath) {
+		file := filepath.Join(dir, t.File())
+		if content, err := ioutil.ReadFile(file); err == nil {
+			return string(content), file
+		} else if !os.IsNotExist(err) {
+			beeLogger.Log.Fatalf("Could not read the template '%s': %s", file, err)
+		}
+	}
+	return t.Text, ""
+}
+
+// Render renders the template, or the file overriding it, with data
+func (t *Template) Render(currpath string, data interface{}) string {
+	text, file := t.Lookup(currpath)
+	name := file
+	if file == "" {
+		name = "built-in " + filepath.ToSlash(t.File())
+	} else {
+		beeLogger.Log.Hintf("Using the template '%s'", file)
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not parse the template: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		beeLogger.Log.Fatalf("Could not render the template: %s", err)
+	}
+	return buf.String()
+}
+
+// ExportTemplates writes the built-in templates to dir, each preceded by the
+// description of its data, as a starting point to override them
+func ExportTemplates(dir string) {
+	plan := &Plan{}
+	for _, t := range templates {
+		content := t.Text
+		if t.Doc != "" {
+			content = "{{/*\n" + strings.TrimSpace(t.Doc) + "\n*/ -}}\n" + content
+		}
+		plan.Add(filepath.Join(dir, t.File()), content)
+	}
+	plan.Apply()
+}
+
+// ListTemplates prints the templates, and the file overriding each of them, if any
+func ListTemplates(currpath string) {
+	for _, t := range templates {
+		if _, file := t.Lookup(currpath); file != "" {
+			beeLogger.Log.Infof("%-32s %s", filepath.ToSlash(t.File()), file)
+		} else {
+			beeLogger.Log.Infof("%-32s built-in", filepath.ToSlash(t.File()))
+		}
+	}
+}
@@ -15,12 +15,14 @@
 package swaggergen
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -94,16 +96,12 @@ var stdlibObject = map[string]string{
 }
 
 func init() {
-	pkgCache = make(map[string]struct{})
-	controllerComments = make(map[string]string)
-	importlist = make(map[string]string)
-	controllerList = make(map[string]map[string]*swagger.Item)
-	modelsList = make(map[string]map[string]swagger.Schema)
-	astPkgs = make([]*ast.Package, 0)
+	resetDocs()
 }
 
 // ParsePackagesFromDir parses packages from a given directory
 func ParsePackagesFromDir(dirpath string) {
+	astPkgs = make([]*ast.Package, 0)
 	c := make(chan error)
 
 	go func() {
@@ -139,17 +137,14 @@ func ParsePackagesFromDir(dirpath string) {
 }
 
 func parsePackageFromDir(path string) error {
-	fileSet := token.NewFileSet()
-	folderPkgs, err := parser.ParseDir(fileSet, path, func(info os.FileInfo) bool {
-		name := info.Name()
-		return !info.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
-	}, parser.ParseComments)
+	folderPkgs, err := parseDir(path)
 	if err != nil {
 		return err
 	}
 
 	for _, v := range folderPkgs {
 		astPkgs = append(astPkgs, v)
+		pkgDirs[v] = path
 	}
 
 	return nil
@@ -157,11 +152,19 @@ func parsePackageFromDir(path string) error {
 
 // GenerateDocs generates documentations for a given path.
 func GenerateDocs(curpath string) {
+	generateDocs(curpath)
+	if _, err := writeDocs(curpath); err != nil {
+		panic(err)
+	}
+}
+
+// generateDocs analyses the router and the controllers of the application
+func generateDocs(curpath string) {
 	fset := token.NewFileSet()
 
 	f, err := parser.ParseFile(fset, filepath.Join(curpath, "routers", "router.go"), nil, parser.ParseComments)
 	if err != nil {
-		beeLogger.Log.Fatalf("Error while parsing router.go: %s", err)
+		fatalf("Error while parsing router.go: %s", err)
 	}
 
 	rootapi.Infos = swagger.Information{}
@@ -208,16 +211,16 @@ func GenerateDocs(curpath string) {
 					var out swagger.Security
 					p := getparams(strings.TrimSpace(s[len("@SecurityDefinition"):]))
 					if len(p) < 2 {
-						beeLogger.Log.Fatalf("Not enough params for security: %d\n", len(p))
+						fatalf("Not enough params for security: %d\n", len(p))
 					}
 					out.Type = p[1]
 					switch out.Type {
 					case "oauth2":
 						if len(p) < 6 {
-							beeLogger.Log.Fatalf("Not enough params for oauth2: %d\n", len(p))
+							fatalf("Not enough params for oauth2: %d\n", len(p))
 						}
 						if !(p[3] == "implicit" || p[3] == "password" || p[3] == "application" || p[3] == "accessCode") {
-							beeLogger.Log.Fatalf("Unknown flow type: %s. Possible values are `implicit`, `password`, `application` or `accessCode`.\n", p[1])
+							fatalf("Unknown flow type: %s. Possible values are `implicit`, `password`, `application` or `accessCode`.\n", p[1])
 						}
 						out.AuthorizationURL = p[2]
 						out.Flow = p[3]
@@ -230,10 +233,10 @@ func GenerateDocs(curpath string) {
 						}
 					case "apiKey":
 						if len(p) < 4 {
-							beeLogger.Log.Fatalf("Not enough params for apiKey: %d\n", len(p))
+							fatalf("Not enough params for apiKey: %d\n", len(p))
 						}
 						if !(p[3] == "header" || p[3] == "query") {
-							beeLogger.Log.Fatalf("Unknown in type: %s. Possible values are `query` or `header`.\n", p[4])
+							fatalf("Unknown in type: %s. Possible values are `query` or `header`.\n", p[4])
 						}
 						out.Name = p[2]
 						out.In = p[3]
@@ -245,7 +248,7 @@ func GenerateDocs(curpath string) {
 							out.Description = strings.Trim(p[2], `" `)
 						}
 					default:
-						beeLogger.Log.Fatalf("Unknown security type: %s. Possible values are `oauth2`, `apiKey` or `basic`.\n", p[1])
+						fatalf("Unknown security type: %s. Possible values are `oauth2`, `apiKey` or `basic`.\n", p[1])
 					}
 					rootapi.SecurityDefinitions[p[0]] = out
 				} else if strings.HasPrefix(s, "@Security") {
@@ -320,27 +323,37 @@ func GenerateDocs(curpath string) {
 			}
 		}
 	}
-	os.Mkdir(path.Join(curpath, "swagger"), 0755)
-	fd, err := os.Create(path.Join(curpath, "swagger", "swagger.json"))
+}
+
+// writeDocs writes swagger.json and swagger.yml. Each file is left
+// untouched if it exists and its content did not change.
+// It returns true if one of the files was written.
+func writeDocs(curpath string) (bool, error) {
+	dt, err := json.MarshalIndent(rootapi, "", "    ")
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	fdyml, err := os.Create(path.Join(curpath, "swagger", "swagger.yml"))
+	dtyml, err := yaml.Marshal(rootapi)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	defer fdyml.Close()
-	defer fd.Close()
-	dt, err := json.MarshalIndent(rootapi, "", "    ")
-	dtyml, erryml := yaml.Marshal(rootapi)
-	if err != nil || erryml != nil {
-		panic(err)
-	}
-	_, err = fd.Write(dt)
-	_, erryml = fdyml.Write(dtyml)
-	if err != nil || erryml != nil {
-		panic(err)
+
+	os.Mkdir(path.Join(curpath, "swagger"), 0755)
+	written := false
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{{"swagger.json", dt}, {"swagger.yml", dtyml}} {
+		fpath := path.Join(curpath, "swagger", file.name)
+		if old, err := ioutil.ReadFile(fpath); err == nil && bytes.Equal(old, file.content) {
+			continue
+		}
+		if err := ioutil.WriteFile(fpath, file.content, 0644); err != nil {
+			return written, err
+		}
+		written = true
 	}
+	return written, nil
 }
 
 // analyseNewNamespace returns version and the others params
@@ -432,16 +445,18 @@ func analyseControllerPkg(vendorPath, localName, pkgpath string) {
 		pps := strings.Split(pkgpath, "/")
 		importlist[pps[len(pps)-1]] = pkgpath
 	}
-	gopaths := bu.GetGOPATHs()
-	if len(gopaths) == 0 {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
 	pkgRealpath := ""
 
 	wg, _ := filepath.EvalSymlinks(filepath.Join(vendorPath, pkgpath))
 	if utils.FileExists(wg) {
 		pkgRealpath = wg
+	} else if found, modPkgPath := bu.SearchModule(filepath.Dir(vendorPath), pkgpath); found {
+		pkgRealpath = modPkgPath
 	} else {
+		gopaths := bu.GetGOPATHs()
+		if len(gopaths) == 0 {
+			fatal("GOPATH environment variable is not set or empty")
+		}
 		wgopath := gopaths
 		for _, wg := range wgopath {
 			wg, _ = filepath.EvalSymlinks(filepath.Join(wg, "src", pkgpath))
@@ -457,16 +472,13 @@ func analyseControllerPkg(vendorPath, localName, pkgpath string) {
 		}
 		pkgCache[pkgpath] = struct{}{}
 	} else {
-		beeLogger.Log.Fatalf("Package '%s' does not exist in the GOPATH or vendor path", pkgpath)
+		fatalf("Package '%s' does not exist in the module, GOPATH or vendor path", pkgpath)
 	}
 
-	fileSet := token.NewFileSet()
-	astPkgs, err := parser.ParseDir(fileSet, pkgRealpath, func(info os.FileInfo) bool {
-		name := info.Name()
-		return !info.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
-	}, parser.ParseComments)
+	docsDirs[pkgRealpath] = struct{}{}
+	astPkgs, err := parseDir(pkgRealpath)
 	if err != nil {
-		beeLogger.Log.Fatalf("Error while parsing dir at '%s': %s", pkgpath, err)
+		fatalf("Error while parsing dir at '%s': %s", pkgpath, err)
 	}
 	for _, pkg := range astPkgs {
 		for _, fl := range pkg.Files {
@@ -504,7 +516,7 @@ func isSystemPackage(pkgpath string) bool {
 		goroot = runtime.GOROOT()
 	}
 	if goroot == "" {
-		beeLogger.Log.Fatalf("GOROOT environment variable is not set or empty")
+		fatalf("GOROOT environment variable is not set or empty")
 	}
 
 	wg, _ := filepath.EvalSymlinks(filepath.Join(goroot, "src", "pkg", pkgpath))
@@ -572,7 +584,7 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 					ss = strings.TrimSpace(ss[pos:])
 					schemaName, pos := peekNextSplitString(ss)
 					if schemaName == "" {
-						beeLogger.Log.Fatalf("[%s.%s] Schema must follow {object} or {array}", controllerName, funcName)
+						fatalf("[%s.%s] Schema must follow {object} or {array}", controllerName, funcName)
 					}
 					if strings.HasPrefix(schemaName, "[]") {
 						schemaName = schemaName[2:]
@@ -609,7 +621,7 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 				para := swagger.Parameter{}
 				p := getparams(strings.TrimSpace(t[len("@Param "):]))
 				if len(p) < 4 {
-					beeLogger.Log.Fatal(controllerName + "_" + funcName + "'s comments @Param should have at least 4 params")
+					fatal(controllerName + "_" + funcName + "'s comments @Param should have at least 4 params")
 				}
 				paramNames := strings.SplitN(p[0], "=>", 2)
 				para.Name = paramNames[0]
@@ -929,6 +941,7 @@ L:
 							continue
 						}
 						parseObject(d, k, &m, &realTypes, astPkgs, packageName)
+						addModelPackage(pkg)
 
 						// When we've found the correct object, we can stop searching
 						break L
@@ -956,7 +969,7 @@ L:
 func parseObject(d *ast.Object, k string, m *swagger.Schema, realTypes *[]string, astPkgs []*ast.Package, packageName string) {
 	ts, ok := d.Decl.(*ast.TypeSpec)
 	if !ok {
-		beeLogger.Log.Fatalf("Unknown type without TypeSec: %v", d)
+		fatalf("Unknown type without TypeSec: %v", d)
 	}
 	// TODO support other types, such as `MapType`, `InterfaceType` etc...
 	switch t := ts.Type.(type) {
@@ -1002,7 +1015,7 @@ func parseIdent(st *ast.Ident, k string, m *swagger.Schema, astPkgs []*ast.Packa
 				if obj.Kind == ast.Con {
 					vs, ok := obj.Decl.(*ast.ValueSpec)
 					if !ok {
-						beeLogger.Log.Fatalf("Unknown type without ValueSpec: %v", vs)
+						fatalf("Unknown type without ValueSpec: %v", vs)
 					}
 
 					ti, ok := vs.Type.(*ast.Ident)
@@ -1200,6 +1213,7 @@ func parseStruct(st *ast.StructType, k string, m *swagger.Schema, realTypes *[]s
 							for nameOfObj, obj := range fl.Scope.Objects {
 								if obj.Name == fmt.Sprint(field.Type) {
 									parseObject(obj, nameOfObj, nm, realTypes, astPkgs, pkg.Name)
+									addModelPackage(pkg)
 								}
 							}
 						}
@@ -1280,7 +1294,7 @@ func getSecurity(t string) (security map[string][]string) {
 	security = make(map[string][]string)
 	p := getparams(strings.TrimSpace(t[len("@Security"):]))
 	if len(p) == 0 {
-		beeLogger.Log.Fatalf("No params for security specified\n")
+		fatalf("No params for security specified\n")
 	}
 	security[p[0]] = make([]string, 0)
 	for i := 1; i < len(p); i++ {
@@ -0,0 +1,168 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package swaggergen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+	"github.com/cisordeng/beego/swagger"
+)
+
+// parsedDir holds the packages of a directory along with
+// the names and modification times of their files
+type parsedDir struct {
+	signature string
+	pkgs      map[string]*ast.Package
+}
+
+// docsError is an error which ends "bee generate docs",
+// but is returned by RegenerateDocs
+type docsError struct {
+	error
+}
+
+var (
+	docsMu      sync.Mutex
+	parsedDirs  = make(map[string]parsedDir)
+	docsDirs    map[string]struct{}     // Directories of the controller and model packages
+	pkgDirs     map[*ast.Package]string // Directories of the parsed packages
+	generated   bool                    // Whether the docs were generated successfully once
+	exitOnError = true
+)
+
+// RegenerateDocs generates the docs again while "bee run" watches the
+// application. Only the directories which changed are parsed again, and
+// errors are returned instead of ending the program.
+// It returns true if swagger.json or swagger.yml was written.
+func RegenerateDocs(curpath string) (written bool, err error) {
+	docsMu.Lock()
+	defer docsMu.Unlock()
+
+	exitOnError = false
+	defer func() {
+		exitOnError = true
+		if e := recover(); e != nil {
+			de, ok := e.(docsError)
+			if !ok {
+				panic(e)
+			}
+			written, err = false, de.error
+		}
+	}()
+
+	resetDocs()
+	ParsePackagesFromDir(curpath)
+	generateDocs(curpath)
+	written, err = writeDocs(curpath)
+	generated = err == nil
+	return written, err
+}
+
+// IsDocsFile reports whether a change to file may change the docs, i.e.
+// whether it belongs to the routers, to a controller package or to a package
+// declaring the models of the docs.
+func IsDocsFile(curpath, file string) bool {
+	docsMu.Lock()
+	defer docsMu.Unlock()
+
+	if !generated {
+		return true
+	}
+	dir := filepath.Dir(file)
+	if dir == filepath.Join(curpath, "routers") {
+		return true
+	}
+	_, ok := docsDirs[dir]
+	return ok
+}
+
+// resetDocs forgets the result of the previous generation
+func resetDocs() {
+	pkgCache = make(map[string]struct{})
+	controllerComments = make(map[string]string)
+	importlist = make(map[string]string)
+	controllerList = make(map[string]map[string]*swagger.Item)
+	modelsList = make(map[string]map[string]swagger.Schema)
+	astPkgs = make([]*ast.Package, 0)
+	docsDirs = make(map[string]struct{})
+	pkgDirs = make(map[*ast.Package]string)
+	rootapi = swagger.Swagger{}
+}
+
+// addModelPackage records the directory of a package whose types were
+// resolved into the models, so that its changes regenerate the docs
+func addModelPackage(pkg *ast.Package) {
+	if dir, ok := pkgDirs[pkg]; ok {
+		docsDirs[dir] = struct{}{}
+	}
+}
+
+// parseDir parses the Go files of dir. The packages are cached
+// until a file of dir is added, removed or modified.
+func parseDir(dir string) (map[string]*ast.Package, error) {
+	signature := dirSignature(dir)
+	if cached, ok := parsedDirs[dir]; ok && cached.signature == signature {
+		return cached.pkgs, nil
+	}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, isGoFile, parser.ParseComments)
+	if err != nil {
+		delete(parsedDirs, dir)
+		return nil, err
+	}
+	parsedDirs[dir] = parsedDir{signature: signature, pkgs: pkgs}
+	return pkgs, nil
+}
+
+// dirSignature lists the Go files of dir with their modification time
+func dirSignature(dir string) string {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var buf strings.Builder
+	for _, info := range fileInfos {
+		if isGoFile(info) {
+			fmt.Fprintf(&buf, "%s:%d;", info.Name(), info.ModTime().UnixNano())
+		}
+	}
+	return buf.String()
+}
+
+func isGoFile(info os.FileInfo) bool {
+	name := info.Name()
+	return !info.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
+}
+
+// fatal and fatalf end the program, unless the docs are generated by RegenerateDocs
+func fatal(message string) {
+	fatalf("%s", message)
+}
+
+func fatalf(message string, vars ...interface{}) {
+	if exitOnError {
+		beeLogger.Log.Fatalf(message, vars...)
+	}
+	panic(docsError{errors.New(strings.TrimSpace(fmt.Sprintf(message, vars...)))})
+}
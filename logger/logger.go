@@ -47,7 +47,7 @@ var (
 )
 var debugMode = os.Getenv("DEBUG_ENABLED") == "1"
 
-var logLevel = levelInfo
+var logLevel int32 = levelInfo
 
 // BeeLogger logs logging records to the specified io.Writer
 type BeeLogger struct {
@@ -167,7 +167,7 @@ func (l *BeeLogger) getColorLevel(level int) string {
 // mustLog logs the message according to the specified level and arguments.
 // It panics in case of an error.
 func (l *BeeLogger) mustLog(level int, message string, args ...interface{}) {
-	if level > logLevel {
+	if int32(level) > atomic.LoadInt32(&logLevel) {
 		return
 	}
 	// Acquire the lock
@@ -273,6 +273,15 @@ func (l *BeeLogger) Successf(message string, vars ...interface{}) {
 	l.mustLog(levelSuccess, message, vars...)
 }
 
+// SetVerbose shows the hint log messages, which are hidden by default
+func (l *BeeLogger) SetVerbose(verbose bool) {
+	level := int32(levelInfo)
+	if verbose {
+		level = levelHint
+	}
+	atomic.StoreInt32(&logLevel, level)
+}
+
 // Hint outputs a hint log message
 func (l *BeeLogger) Hint(message string) {
 	l.mustLog(levelHint, message)
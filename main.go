@@ -67,9 +67,9 @@ func main() {
 
 			config.LoadConfig()
 
-			// Check if current directory is inside the GOPATH,
+			// Check if current directory is inside the GOPATH or a Go module,
 			// if so parse the packages inside it.
-			if utils.IsInGOPATH(currentpath) && cmd.IfGenerateDocs(c.Name(), args) {
+			if (utils.IsInGOPATH(currentpath) || utils.IsInModule(currentpath)) && cmd.IfGenerateDocs(c.Name(), args) {
 				swaggergen.ParsePackagesFromDir(currentpath)
 			}
 			os.Exit(c.Run(c, args))
@@ -0,0 +1,67 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// AtomicBool is a boolean flag which can be read and changed from
+// several goroutines. It implements the flag.Value interface.
+type AtomicBool struct {
+	v int32
+}
+
+func (b *AtomicBool) String() string {
+	return strconv.FormatBool(b.Get())
+}
+
+func (b *AtomicBool) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	b.Store(v)
+	return nil
+}
+
+// IsBoolFlag allows the flag to be given without a value
+func (b *AtomicBool) IsBoolFlag() bool {
+	return true
+}
+
+// Get returns the value of the flag
+func (b *AtomicBool) Get() bool {
+	return atomic.LoadInt32(&b.v) == 1
+}
+
+// Store changes the value of the flag
+func (b *AtomicBool) Store(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&b.v, i)
+}
+
+// Toggle inverts the value of the flag and returns the new value
+func (b *AtomicBool) Toggle() bool {
+	for {
+		old := atomic.LoadInt32(&b.v)
+		if atomic.CompareAndSwapInt32(&b.v, old, 1-old) {
+			return old == 0
+		}
+	}
+}
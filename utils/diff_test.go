@@ -0,0 +1,58 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	numbers := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"created", "", "a\nb\n", "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"emptied", "a\n", "", "@@ -1,1 +0,0 @@\n-a\n"},
+		{"changed line", "a\nb\nc\n", "a\nx\nc\n", "@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"added line", "a\nc\n", "a\nb\nc\n", "@@ -1,2 +1,3 @@\n a\n+b\n c\n"},
+		{
+			"close changes in one hunk",
+			"a\nb\nc\nd\ne\n", "a\nB\nc\nD\ne\n",
+			"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n-d\n+D\n e\n",
+		},
+		{
+			"distant changes in two hunks",
+			numbers, strings.Replace(strings.Replace(numbers, "1\n", "x\n", 1), "10\n", "y\n", 1),
+			"@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+y\n",
+		},
+		{
+			"context trimmed around a change",
+			numbers, strings.Replace(numbers, "5\n", "five\n", 1),
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if want != "" {
+			want = "--- a.go\n+++ b.go\n" + want
+		}
+		if got := UnifiedDiff("a.go", "b.go", tt.a, tt.b); got != want {
+			t.Errorf("%s: UnifiedDiff() =\n%s\nwant\n%s", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultRunmode is the runmode of a Beego application when BEEGO_RUNMODE is not set
+const DefaultRunmode = "dev"
+
+var envKeyRegExp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// EnvFiles returns the names of the .env files read for the runmode,
+// from the lowest to the highest precedence
+func EnvFiles(runmode string) []string {
+	if runmode == "" {
+		runmode = DefaultRunmode
+	}
+	return []string{".env", ".env.local", ".env." + runmode, ".env." + runmode + ".local"}
+}
+
+// IsEnvFile reports whether the file is one of the .env files of an application
+func IsEnvFile(name string) bool {
+	base := filepath.Base(name)
+	return base == ".env" || strings.HasPrefix(base, ".env.")
+}
+
+// LoadEnvFiles reads the .env files of dir for the runmode and returns their
+// variables as KEY=VALUE pairs. The variables of a file override those of the
+// previous ones, but not those already set in the environment of bee.
+func LoadEnvFiles(dir, runmode string) ([]string, error) {
+	vars := make(map[string]string)
+	var keys []string
+	for _, name := range EnvFiles(runmode) {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		err = ParseEnv(f, func(key, value string) {
+			if _, ok := vars[key]; !ok {
+				keys = append(keys, key)
+			}
+			vars[key] = value
+		}, func(key string) (string, bool) {
+			if value, ok := os.LookupEnv(key); ok {
+				return value, true
+			}
+			value, ok := vars[key]
+			return value, ok
+		})
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	var env []string
+	for _, key := range keys {
+		if _, ok := os.LookupEnv(key); !ok {
+			env = append(env, key+"="+vars[key])
+		}
+	}
+	return env, nil
+}
+
+// ParseEnv parses a .env file and calls set for each variable, in order.
+// The values may be single-quoted (taken literally), double-quoted (with
+// escape sequences and spanning several lines) or unquoted. References to
+// ${VAR}, ${VAR:-default} and $VAR in unquoted and double-quoted values
+// are expanded with lookup.
+func ParseEnv(r io.Reader, set func(key, value string), lookup func(string) (string, bool)) error {
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("line %d: missing '=' in '%s'", lineno, line)
+		}
+		key := strings.TrimSpace(line[:i])
+		if !envKeyRegExp.MatchString(key) {
+			return fmt.Errorf("line %d: invalid variable name '%s'", lineno, key)
+		}
+		value := strings.TrimSpace(line[i+1:])
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated single-quoted value of '%s'", lineno, key)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// Double-quoted values continue on the next lines until the closing quote
+			value = value[1:]
+			start := lineno
+			for closingQuote(value) < 0 {
+				if !scanner.Scan() {
+					return fmt.Errorf("line %d: unterminated double-quoted value of '%s'", start, key)
+				}
+				lineno++
+				value += "\n" + scanner.Text()
+			}
+			value = expandEnv(value[:closingQuote(value)], true, lookup)
+		default:
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+			value = expandEnv(value, false, lookup)
+		}
+		set(key, value)
+	}
+	return scanner.Err()
+}
+
+// closingQuote returns the index of the first unescaped double quote of s, or -1
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// expandEnv expands ${VAR}, ${VAR:-default} and $VAR in s, and replaces the
+// escape sequences of a double-quoted value if quoted is true, in a single pass
+// so that an escaped backslash does not escape the following dollar sign.
+// Otherwise only \$ is an escape sequence. Undefined variables are replaced
+// by an empty string.
+func expandEnv(s string, quoted bool, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (quoted || s[i+1] == '$'):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.Index(s[i:], "}")
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			name, def := s[i+2:i+end], ""
+			if j := strings.Index(name, ":-"); j >= 0 {
+				name, def = name[:j], name[j+2:]
+			}
+			if value, ok := lookup(name); ok && value != "" {
+				b.WriteString(value)
+			} else {
+				b.WriteString(def)
+			}
+			i += end
+		case s[i] == '$':
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || 'a' <= s[j] && s[j] <= 'z' || 'A' <= s[j] && s[j] <= 'Z' || j > i+1 && '0' <= s[j] && s[j] <= '9') {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte('$')
+				continue
+			}
+			value, _ := lookup(s[i+1 : j])
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
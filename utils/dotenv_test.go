@@ -0,0 +1,92 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lookupMap returns a lookup function reading the variables of env
+func lookupMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+		err     string
+	}{
+		{"", nil, ""},
+		{"# comment\n\nA=1\n", []string{"A=1"}, ""},
+		{"export A = 1\nB=", []string{"A=1", "B="}, ""},
+		{"A=1 # comment\nB=a#b", []string{"A=1", "B=a#b"}, ""},
+		{"A='${HOME} \\n' # comment", []string{"A=${HOME} \\n"}, ""},
+		{`A="a\tb\"c\"" # comment`, []string{"A=a\tb\"c\""}, ""},
+		{"A=\"first\nsecond\"\nB=2", []string{"A=first\nsecond", "B=2"}, ""},
+		{"A=1\nB=${A}2\nC=\"$A-$B\"", []string{"A=1", "B=12", "C=1-12"}, ""},
+		{"A=${HOME}", []string{"A=/home/bee"}, ""},
+		{"A", nil, "line 1: missing '=' in 'A'"},
+		{"\n1A=x", nil, "line 2: invalid variable name '1A'"},
+		{"A='x", nil, "line 1: unterminated single-quoted value of 'A'"},
+		{"A=\"x\ny", nil, "line 1: unterminated double-quoted value of 'A'"},
+	}
+	for _, tt := range tests {
+		var got []string
+		vars := map[string]string{"HOME": "/home/bee"}
+		err := ParseEnv(strings.NewReader(tt.content), func(key, value string) {
+			vars[key] = value
+			got = append(got, key+"="+value)
+		}, lookupMap(vars))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ParseEnv(%q) error = %v, want %s", tt.content, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEnv(%q) error = %v", tt.content, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEnv(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	lookup := lookupMap(map[string]string{"A": "1", "B_2": "two", "EMPTY": ""})
+	tests := []struct {
+		s      string
+		quoted bool
+		want   string
+	}{
+		{"plain", false, "plain"},
+		{"$A ${A} $B_2", false, "1 1 two"},
+		{"${UNSET}|$UNSET|${EMPTY}", false, "||"},
+		{"${UNSET:-x} ${EMPTY:-y} ${A:-z}", false, "x y 1"},
+		{"$A0 $1 $", false, " $1 $"},
+		{"${A", false, "${A"},
+		{`\$A \n`, false, `$A \n`},
+		{`\$A \n\t\r \\$A \"`, true, "$A \n\t\r \\1 \""},
+	}
+	for _, tt := range tests {
+		if got := expandEnv(tt.s, tt.quoted, lookup); got != tt.want {
+			t.Errorf("expandEnv(%q, %v) = %q, want %q", tt.s, tt.quoted, got, tt.want)
+		}
+	}
+}
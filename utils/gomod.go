@@ -0,0 +1,137 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GetModule looks for a go.mod file in thePath or any of its parents.
+// It returns a boolean, the module root directory and the module path.
+// Modules are ignored when GO111MODULE is set to "off".
+func GetModule(thePath string) (bool, string, string) {
+	if os.Getenv("GO111MODULE") == "off" {
+		return false, "", ""
+	}
+	dir, err := filepath.Abs(thePath)
+	if err != nil {
+		return false, "", ""
+	}
+	for {
+		modFile := filepath.Join(dir, "go.mod")
+		if fi, err := os.Stat(modFile); err == nil && !fi.IsDir() {
+			if modPath := readModulePath(modFile); modPath != "" {
+				return true, dir, modPath
+			}
+			return false, "", ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false, "", ""
+		}
+		dir = parent
+	}
+}
+
+// IsInModule checks whether the path is inside of a Go module or not
+func IsInModule(thePath string) bool {
+	found, _, _ := GetModule(thePath)
+	return found
+}
+
+// GetImportPath returns the import path of the package located in thePath.
+// The enclosing Go module is used if there is one, the GOPATH otherwise.
+func GetImportPath(thePath string) (string, error) {
+	absPath, err := filepath.Abs(thePath)
+	if err != nil {
+		return "", err
+	}
+	if found, root, modPath := GetModule(absPath); found {
+		rel, err := filepath.Rel(root, absPath)
+		if err != nil {
+			return "", err
+		}
+		if rel == "." {
+			return modPath, nil
+		}
+		return modPath + "/" + filepath.ToSlash(rel), nil
+	}
+	for _, gopath := range GetGOPATHs() {
+		gosrcpath := filepath.Join(gopath, "src")
+		if rel, err := filepath.Rel(gosrcpath, absPath); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is neither inside a Go module nor inside $GOPATH/src", absPath)
+}
+
+// UseModules reports whether a new application created in thePath
+// should be set up as a Go module rather than a GOPATH package.
+func UseModules(thePath string) bool {
+	switch os.Getenv("GO111MODULE") {
+	case "off":
+		return false
+	case "on":
+		return true
+	}
+	return IsInModule(thePath) || !IsInGOPATH(thePath)
+}
+
+// SearchModule looks up the package pkg inside the Go module enclosing thePath.
+// It returns a boolean and the package's full path.
+func SearchModule(thePath, pkg string) (bool, string) {
+	found, root, modPath := GetModule(thePath)
+	if !found {
+		return false, ""
+	}
+	if pkg != modPath && !strings.HasPrefix(pkg, modPath+"/") {
+		return false, ""
+	}
+	pkgPath := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(pkg, modPath)))
+	if IsExist(pkgPath) {
+		return true, pkgPath
+	}
+	return false, ""
+}
+
+// readModulePath returns the path declared by the module directive of a go.mod file
+func readModulePath(modFile string) string {
+	f, err := os.Open(modFile)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted
+		}
+		return fields[1]
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadModulePath(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"module example.com/app\n", "example.com/app"},
+		{"// The application\nmodule example.com/app // comment\n\ngo 1.13\n", "example.com/app"},
+		{"module \"example.com/quoted\"\n", "example.com/quoted"},
+		{"go 1.13\n\nrequire example.com/dep v1.0.0\n", ""},
+		{"", ""},
+	}
+	dir := t.TempDir()
+	modFile := filepath.Join(dir, "go.mod")
+	for _, tt := range tests {
+		if err := ioutil.WriteFile(modFile, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := readModulePath(modFile); got != tt.want {
+			t.Errorf("readModulePath(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+	if got := readModulePath(filepath.Join(dir, "missing.mod")); got != "" {
+		t.Errorf("readModulePath of a missing file = %q, want \"\"", got)
+	}
+}
+
+func TestGetImportPath(t *testing.T) {
+	gopath := t.TempDir()
+	mod := filepath.Join(t.TempDir(), "app")
+	files := map[string]string{
+		filepath.Join(mod, "go.mod"):                                  "module example.com/app\n",
+		filepath.Join(mod, "models", "user.go"):                       "package models\n",
+		filepath.Join(gopath, "src", "example.com", "old", "main.go"): "package main\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("GOPATH", gopath)
+
+	tests := []struct {
+		path    string
+		modules string // Value of GO111MODULE
+		want    string
+		wantErr bool
+	}{
+		{mod, "on", "example.com/app", false},
+		{filepath.Join(mod, "models"), "on", "example.com/app/models", false},
+		{filepath.Join(mod, "controllers"), "on", "example.com/app/controllers", false},
+		{filepath.Join(gopath, "src", "example.com", "old"), "on", "example.com/old", false},
+		{filepath.Join(mod, "models"), "off", "", true},
+		{filepath.Join(gopath, "src"), "off", "", true},
+		{t.TempDir(), "on", "", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("GO111MODULE", tt.modules)
+		got, err := GetImportPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetImportPath(%q) with GO111MODULE=%s error = %v, want error %v", tt.path, tt.modules, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GetImportPath(%q) with GO111MODULE=%s = %q, want %q", tt.path, tt.modules, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	beeLogger "github.com/cisordeng/bee/logger"
+)
+
+// IgnoreFile is the name of the file listing the paths bee should ignore
+const IgnoreFile = ".beeignore"
+
+// IgnoreMatcher matches slash-separated paths, relative to the application
+// directory, against gitignore-style patterns:
+//
+//   - a pattern without a slash matches a name at any depth,
+//     otherwise it is relative to the application directory;
+//   - "*" and "?" do not match slashes while "**" matches any number of directories;
+//   - a trailing slash only matches directories;
+//   - a leading "!" includes again a path excluded by a previous pattern.
+//
+// The last matching pattern wins, and the content of an excluded directory
+// is always excluded.
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewIgnoreMatcher returns a matcher for the given patterns
+func NewIgnoreMatcher(patterns ...string) *IgnoreMatcher {
+	m := new(IgnoreMatcher)
+	m.Add(patterns...)
+	return m
+}
+
+// LoadIgnoreMatcher returns a matcher for the default patterns followed by
+// the patterns of the .beeignore file of appPath and the exclude patterns,
+// usually those of the 'watch' section of the Beefile. The include patterns
+// are added last as negations.
+func LoadIgnoreMatcher(appPath string, defaults, exclude, include []string) *IgnoreMatcher {
+	m := NewIgnoreMatcher(defaults...)
+	patterns, err := ReadIgnoreFile(filepath.Join(appPath, IgnoreFile))
+	if err != nil && !os.IsNotExist(err) {
+		beeLogger.Log.Warnf("Failed to read '%s': %s", IgnoreFile, err)
+	}
+	m.Add(patterns...)
+	m.Add(exclude...)
+	for _, p := range include {
+		m.Add("!" + p)
+	}
+	return m
+}
+
+// ReadIgnoreFile returns the patterns of a gitignore-style file,
+// skipping the blank lines and the comments.
+func ReadIgnoreFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// Add appends patterns to the matcher. Invalid patterns are skipped.
+func (m *IgnoreMatcher) Add(patterns ...string) {
+	for _, p := range patterns {
+		var ip ignorePattern
+		if strings.HasPrefix(p, "!") {
+			ip.negate = true
+			p = p[1:]
+		} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+			p = p[1:]
+		}
+		if strings.HasSuffix(p, "/") {
+			ip.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(ignorePatternToRegexp(p))
+		if err != nil {
+			beeLogger.Log.Warnf("Invalid ignore pattern '%s': %s", p, err)
+			continue
+		}
+		ip.re = re
+		m.patterns = append(m.patterns, ip)
+	}
+}
+
+// Match reports whether relPath is excluded. isDir tells
+// whether relPath is a directory, for the directory-only patterns.
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	relPath = strings.Trim(path.Clean(filepath.ToSlash(relPath)), "/")
+	if relPath == "." || relPath == "" {
+		return false
+	}
+
+	// The content of an excluded directory cannot be included again
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(relPath, isDir)
+}
+
+// match applies the patterns to relPath only, the last matching one wins
+func (m *IgnoreMatcher) match(relPath string, isDir bool) bool {
+	excluded := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(relPath) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// ignorePatternToRegexp translates a gitignore-style pattern to a regular expression
+func ignorePatternToRegexp(p string) string {
+	var buf strings.Builder
+	buf.WriteString("^")
+	if !strings.Contains(p, "/") {
+		buf.WriteString("(?:.*/)?")
+	}
+	p = strings.TrimPrefix(p, "/")
+
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					i++
+					buf.WriteString("(?:.*/)?")
+				} else {
+					buf.WriteString(".*")
+				}
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(p) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(string(p[i])))
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString("$")
+	return buf.String()
+}
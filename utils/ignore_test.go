@@ -0,0 +1,91 @@
+// Copyright 2019 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+package utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcherMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{nil, "main.go", false, false},
+		{[]string{"*.log"}, "app.log", false, true},
+		{[]string{"*.log"}, "logs/app.log", false, true},
+		{[]string{"*.log"}, "app.go", false, false},
+		{[]string{"/build"}, "build", true, true},
+		{[]string{"/build"}, "cmd/build", true, false},
+		{[]string{"build/"}, "build", false, false},
+		{[]string{"build/"}, "cmd/build", true, true},
+		{[]string{"build/"}, "cmd/build/main.go", false, true},
+		{[]string{"docs/*.json"}, "docs/swagger.json", false, true},
+		{[]string{"docs/*.json"}, "docs/v1/swagger.json", false, false},
+		{[]string{"docs/**/*.json"}, "docs/v1/swagger.json", false, true},
+		{[]string{"docs/**/*.json"}, "docs/swagger.json", false, true},
+		{[]string{"**/testdata"}, "a/b/testdata", true, true},
+		{[]string{"static/**"}, "static/js/app.js", false, true},
+		{[]string{"file?.go"}, "file1.go", false, true},
+		{[]string{"file?.go"}, "file10.go", false, false},
+		{[]string{"[ab].go"}, "a.go", false, true},
+		{[]string{"[!ab].go"}, "a.go", false, false},
+		{[]string{"[!ab].go"}, "c.go", false, true},
+		{[]string{"*.go", "!main.go"}, "main.go", false, false},
+		{[]string{"*.go", "!main.go"}, "app.go", false, true},
+		{[]string{"!main.go", "*.go"}, "main.go", false, true},
+		{[]string{"vendor", "!vendor/keep.go"}, "vendor/keep.go", false, true},
+		{[]string{`\!important`}, "!important", false, true},
+		{[]string{"*.log"}, "./logs/../app.log", false, true},
+		{[]string{"*"}, ".", true, false},
+	}
+	for _, tt := range tests {
+		m := NewIgnoreMatcher(tt.patterns...)
+		if got := m.Match(filepath.FromSlash(tt.path), tt.isDir); got != tt.want {
+			t.Errorf("patterns %q: Match(%q, %v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestLoadIgnoreMatcher(t *testing.T) {
+	dir := t.TempDir()
+	content := "# Generated files\n*.pb.go\n\n!keep.pb.go\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, IgnoreFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := LoadIgnoreMatcher(dir, []string{"*docs"}, []string{"tmp"}, []string{"docs", "tmp/keep"})
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"api.pb.go", false, true},
+		{"keep.pb.go", false, false},
+		{"tmp", true, true},
+		{"tmp/keep", false, true},
+		{"docs", true, false},
+		{"apidocs", true, true},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
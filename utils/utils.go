@@ -16,6 +16,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -350,6 +351,15 @@ func GoCommand(command string, args ...string) error {
 	return goBuild.Run()
 }
 
+// ShellCommand returns a command running the passed command line
+// with the shell of the system
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
 // SplitQuotedFields is like strings.Fields but ignores spaces
 // inside areas surrounded by single quotes.
 // To specify a single quote use backslash to escape it: '\''